@@ -1,15 +1,28 @@
 package mailrucloud
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
 	"strings"
+	"sync"
 )
 
+// AuthStateChangedEventHandler обработчик события изменения состояния авторизации
+type AuthStateChangedEventHandler func(state AuthState)
+
+// CredentialProvider источник учетных данных аккаунта, например менеджер секретов.
+// Credentials вызывается при каждом входе, поэтому смена пароля подхватывается без пересоздания Account
+type CredentialProvider interface {
+	Credentials() (email, password string, err error)
+}
+
 // Account определяет аккаунт Mail.ru
 type Account struct {
 	// Email логин как email
@@ -18,26 +31,92 @@ type Account struct {
 	Password string
 	// ActivatedTariffs список активированных тарифов для аккаунта
 	ActivatedTariffs []*Rate
+	// Logger журнал для предупреждений и диагностических сообщений (nil отключает журналирование).
+	// Повторные попытки загрузки, возобновления скачивания и создания папок записываются с атрибутами
+	// operation, path, attempt или attempts и error
+	Logger *slog.Logger
 	// AuthToken токен авторизации
 	authToken string
 	// httpClient HTTP клиент
 	httpClient *http.Client
 	// cookies контейнер cookies
 	cookies *cookiejar.Jar
+	// authStateChanged обработчик события изменения состояния авторизации
+	authStateChanged AuthStateChangedEventHandler
+	// authorized указывает, что последняя проверка авторизации была успешной
+	authorized bool
+	// fallbackUploadSizeLimit лимит размера загрузки, определенный по квоте диска при отсутствии тарифов
+	fallbackUploadSizeLimit int64
+	// transport транспорт HTTP клиента, общий для всех запросов аккаунта, чтобы соединения переиспользовались
+	transport *http.Transport
+	// cloudBaseURL базовый адрес облака (пустой - BaseMailRuCloud)
+	cloudBaseURL string
+	// authBaseURL базовый адрес авторизации (пустой - BaseMailRuAuth)
+	authBaseURL string
+	// authDomain домен, передаваемый при авторизации (пустой - "mail.ru" или домен из email)
+	authDomain string
+	// authDomainFromEmail включает передачу домена из email, если authDomain не задан
+	authDomainFromEmail bool
+	// responseDecoder пользовательский декодер ответов API (nil - deserializeJSON)
+	responseDecoder func(data []byte, target interface{}) error
+	// credentials источник учетных данных для входа (nil - Email и Password аккаунта)
+	credentials CredentialProvider
+	// acceptLanguage значение заголовка Accept-Language запросов
+	acceptLanguage string
+	// defaultHeaders пользовательские заголовки, добавляемые ко всем запросам (см. CloudClient.SetDefaultHeaders)
+	defaultHeaders http.Header
+	// requestDoer выполняет HTTP запросы аккаунта через CloudClient, чтобы они учитывались ограничением
+	// одновременных запросов, статистикой и метриками клиента (nil - запросы выполняются httpClient)
+	requestDoer func(req *http.Request) (*http.Response, error)
+	// authMu защищает authToken, httpClient, requestDoer, authorized и authStateChanged от одновременного
+	// изменения при обновлении авторизации во время запросов из других горутин
+	authMu sync.RWMutex
+	// refreshMu защищает refreshing
+	refreshMu sync.Mutex
+	// refreshing выполняющееся обновление авторизации, результата которого ожидают одновременные
+	// вызовы (nil, если обновление не выполняется)
+	refreshing *authRefresh
+}
+
+// authRefresh обновление авторизации, выполняемое одним вызовом для всех одновременных запросов
+type authRefresh struct {
+	// done закрывается по завершении обновления
+	done chan struct{}
+	// err результат обновления
+	err error
 }
 
+const (
+	// uploadSizeLimit2GB лимит размера загрузки для бесплатных аккаунтов
+	uploadSizeLimit2GB = int64(2048 * 1024 * 1024)
+	// uploadSizeLimit32GB лимит размера загрузки для аккаунтов с платным тарифом
+	uploadSizeLimit32GB = int64(32768 * 1024 * 1024)
+	// freeDiskQuota объем диска бесплатного аккаунта
+	freeDiskQuota = int64(8 * 1024 * 1024 * 1024)
+)
+
 // NewAccount создает новый экземпляр Account
-func NewAccount(email, password string) *Account {
+func NewAccount(email, password string, opts ...AccountOption) *Account {
 	jar, _ := cookiejar.New(nil)
-	return &Account{
-		Email:    email,
-		Password: password,
-		cookies:  jar,
+	account := &Account{
+		Email:          email,
+		Password:       password,
+		cookies:        jar,
+		transport:      newDefaultTransport(),
+		acceptLanguage: defaultAcceptLanguage,
 	}
+	for _, opt := range opts {
+		opt(account)
+	}
+	return account
 }
 
 // Has2GBUploadSizeLimit возвращает true, если включен лимит размера загрузки 2GB для аккаунта
 func (a *Account) Has2GBUploadSizeLimit() bool {
+	if len(a.ActivatedTariffs) == 0 && a.fallbackUploadSizeLimit > 0 {
+		return a.fallbackUploadSizeLimit <= uploadSizeLimit2GB
+	}
+
 	for _, rate := range a.ActivatedTariffs {
 		if rate.ID != "ZERO" {
 			return false
@@ -46,28 +125,121 @@ func (a *Account) Has2GBUploadSizeLimit() bool {
 	return true
 }
 
+// NewAccountWithToken создает Account с уже полученными токеном авторизации и cookies сессии
+// (например, из Token и Cookies другого экземпляра после Login), без входа по паролю
+func NewAccountWithToken(email, token string, cookies []*http.Cookie, opts ...AccountOption) *Account {
+	account := NewAccount(email, "", opts...)
+	account.setAuthToken(token)
+	account.initHttpClient(account.cloudURL())
+	for _, base := range []string{account.cloudURL(), account.authURL()} {
+		if u, err := url.Parse(base); err == nil {
+			account.cookies.SetCookies(u, cookies)
+		}
+	}
+	return account
+}
+
+// Token возвращает текущий токен авторизации (пустой до входа)
+func (a *Account) Token() string {
+	return a.getAuthToken()
+}
+
+// Cookies возвращает cookies сессии для облака и авторизации, необходимые вместе с Token
+// для создания клиента через NewCloudClientWithToken
+func (a *Account) Cookies() []*http.Cookie {
+	var cookies []*http.Cookie
+	seen := map[string]bool{}
+	for _, base := range []string{a.cloudURL(), a.authURL()} {
+		u, err := url.Parse(base)
+		if err != nil {
+			continue
+		}
+		for _, cookie := range a.cookies.Cookies(u) {
+			if !seen[cookie.Name] {
+				seen[cookie.Name] = true
+				cookies = append(cookies, cookie)
+			}
+		}
+	}
+	return cookies
+}
+
+// HTTPClient возвращает HTTP клиент сессии аккаунта с cookies авторизации (nil до Login или
+// NewAccountWithToken). Сессия Mail.ru общая для сервисов портала, поэтому клиент можно использовать
+// для запросов к API, которые библиотека не поддерживает, например к почте. Заголовок с Token
+// такие запросы должны добавлять сами
+func (a *Account) HTTPClient() *http.Client {
+	return a.getHttpClient()
+}
+
+// cloudURL возвращает базовый адрес облака
+func (a *Account) cloudURL() string {
+	if a.cloudBaseURL != "" {
+		return a.cloudBaseURL
+	}
+	return BaseMailRuCloud
+}
+
+// authURL возвращает базовый адрес авторизации
+func (a *Account) authURL() string {
+	if a.authBaseURL != "" {
+		return a.authBaseURL
+	}
+	return BaseMailRuAuth
+}
+
+// getAuthDomain возвращает домен для авторизации: заданный явно через WithAuthDomain, домен из email
+// при включенном WithAuthDomainFromEmail (например, корпоративный домен Mail.ru для бизнеса)
+// или "mail.ru" по умолчанию
+func (a *Account) getAuthDomain() string {
+	if a.authDomain != "" {
+		return a.authDomain
+	}
+	if a.authDomainFromEmail {
+		if at := strings.LastIndex(a.Email, "@"); at != -1 && at < len(a.Email)-1 {
+			return strings.ToLower(a.Email[at+1:])
+		}
+	}
+	return "mail.ru"
+}
+
+// publicLinkPrefix возвращает начало публичных ссылок облака аккаунта
+func (a *Account) publicLinkPrefix() string {
+	if a == nil || a.cloudBaseURL == "" {
+		return PublicLink
+	}
+	return a.cloudBaseURL + "/public/"
+}
+
+// buildPublicLink строит полную публичную ссылку по токену weblink
+func (a *Account) buildPublicLink(weblink string) string {
+	if weblink == "" {
+		return ""
+	}
+	return a.publicLinkPrefix() + weblink
+}
+
 // performAuth выполняет авторизацию на сервере Mail.ru
-func (a *Account) performAuth() error {
-	a.initHttpClient(BaseMailRuAuth)
+func (a *Account) performAuth(ctx context.Context) error {
+	a.initHttpClient(a.authURL())
 
-	authURL := BaseMailRuAuth + Auth
+	authURL := a.authURL() + Auth
 	formData := url.Values{}
 	formData.Set("Login", a.Email)
-	formData.Set("Domain", "mail.ru")
+	formData.Set("Domain", a.getAuthDomain())
 	formData.Set("Password", a.Password)
 
-	req, err := http.NewRequest("POST", authURL, strings.NewReader(formData.Encode()))
+	req, err := a.newRequest(ctx, "POST", authURL, strings.NewReader(formData.Encode()))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := a.httpClient.Do(req)
+	resp, err := a.do(req)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("авторизация не удалась: статус %d", resp.StatusCode)
@@ -76,19 +248,18 @@ func (a *Account) performAuth() error {
 }
 
 // ensureSDCCookies обеспечивает получение SDC cookies
-func (a *Account) ensureSDCCookies() error {
-	sdcURL := BaseMailRuAuth + EnsureSdc
-	req, err := http.NewRequest("GET", sdcURL, nil)
+func (a *Account) ensureSDCCookies(ctx context.Context) error {
+	sdcURL := a.authURL() + strings.Replace(EnsureSdc, BaseMailRuCloud, a.cloudURL(), 1)
+	req, err := a.newRequest(ctx, "GET", sdcURL, nil)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := a.httpClient.Do(req)
+	resp, err := a.do(req)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("получение SDC cookies не удалось: статус %d", resp.StatusCode)
@@ -96,22 +267,24 @@ func (a *Account) ensureSDCCookies() error {
 	return nil
 }
 
-// fetchAuthToken получает токен авторизации
-func (a *Account) fetchAuthToken() error {
-	a.initHttpClient(BaseMailRuCloud)
+// fetchAuthToken получает токен авторизации. HTTP клиент не пересоздается, так как токен
+// обновляется во время запросов из других горутин, использующих тот же клиент
+func (a *Account) fetchAuthToken(ctx context.Context) error {
+	if a.getHttpClient() == nil {
+		a.initHttpClient(a.cloudURL())
+	}
 
-	tokenURL := BaseMailRuCloud + AuthTokenURL
-	req, err := http.NewRequest("GET", tokenURL, nil)
+	tokenURL := a.cloudURL() + AuthTokenURL
+	req, err := a.newRequest(ctx, "GET", tokenURL, nil)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := a.httpClient.Do(req)
+	resp, err := a.do(req)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -127,18 +300,25 @@ func (a *Account) fetchAuthToken() error {
 		return err
 	}
 
-	a.authToken = authTokenResp.Body.Token
-	if a.authToken == "" {
+	if authTokenResp.Body.Token == "" {
 		return fmt.Errorf("токен не найден в ответе")
 	}
+	a.setAuthToken(authTokenResp.Body.Token)
 	return nil
 }
 
-// loadActivatedRates загружает активированные тарифы
-func (a *Account) loadActivatedRates() error {
-	rates, err := a.getRates()
+// loadActivatedRates загружает активированные тарифы.
+// Если тарифы получить не удалось или их нет, лимит загрузки определяется по квоте диска
+func (a *Account) loadActivatedRates(ctx context.Context) error {
+	a.ActivatedTariffs = nil
+	a.fallbackUploadSizeLimit = 0
+
+	rates, err := a.getRates(ctx)
 	if err != nil {
-		return err
+		if ctx.Err() != nil {
+			return err
+		}
+		a.logWarn("не удалось получить тарифы аккаунта", "error", err)
 	}
 
 	var activatedRates []*Rate
@@ -148,37 +328,198 @@ func (a *Account) loadActivatedRates() error {
 		}
 	}
 	a.ActivatedTariffs = activatedRates
+
+	if len(activatedRates) == 0 {
+		a.logWarn("у аккаунта нет активных тарифов, лимит загрузки определяется по квоте диска")
+		a.fallbackUploadSizeLimit = a.detectUploadSizeLimit(ctx)
+	}
 	return nil
 }
 
+// detectUploadSizeLimit определяет лимит размера загрузки по квоте диска:
+// квота больше бесплатной означает платный тариф
+func (a *Account) detectUploadSizeLimit(ctx context.Context) int64 {
+	diskUsage, err := a.getDiskUsageInternal(ctx, false)
+	if err != nil {
+		a.logWarn("не удалось получить квоту диска", "error", err)
+		return 0
+	}
+
+	if diskUsage.Total.DefaultValue > freeDiskQuota {
+		return uploadSizeLimit32GB
+	}
+	return uploadSizeLimit2GB
+}
+
+// logWarn записывает предупреждение в журнал, если он задан
+func (a *Account) logWarn(msg string, args ...any) {
+	if a.Logger != nil {
+		a.Logger.Warn(msg, args...)
+	}
+}
+
 // Login выполняет вход в облачный сервер
 func (a *Account) Login() error {
-	if err := a.checkAuthorization(true); err != nil {
+	return a.LoginContext(context.Background())
+}
+
+// LoginContext выполняет вход в облачный сервер с учетом контекста
+func (a *Account) LoginContext(ctx context.Context) error {
+	if err := a.loadCredentials(); err != nil {
+		return err
+	}
+
+	if err := a.checkAuthorization(ctx, true); err != nil {
 		return err
 	}
 
-	if err := a.performAuth(); err != nil {
+	if err := a.performAuth(ctx); err != nil {
 		return err
 	}
 
-	if err := a.ensureSDCCookies(); err != nil {
+	if err := a.ensureSDCCookies(ctx); err != nil {
 		return err
 	}
 
-	if err := a.fetchAuthToken(); err != nil {
+	if err := a.fetchAuthToken(ctx); err != nil {
 		return err
 	}
 
-	if err := a.loadActivatedRates(); err != nil {
+	if err := a.loadActivatedRates(ctx); err != nil {
 		return err
 	}
 
+	a.setAuthState(AuthStateLoggedIn)
 	return nil
 }
 
+// loadCredentials получает актуальные учетные данные из CredentialProvider, если он задан
+func (a *Account) loadCredentials() error {
+	if a.credentials == nil {
+		return nil
+	}
+
+	email, password, err := a.credentials.Credentials()
+	if err != nil {
+		return &NotAuthorizedError{
+			Message: fmt.Sprintf("Не удалось получить учетные данные: %v", err),
+			Source:  "CredentialProvider",
+		}
+	}
+	// Поля меняются только при смене учетных данных, так как их читают запросы из других горутин
+	if a.Email != email {
+		a.Email = email
+	}
+	if a.Password != password {
+		a.Password = password
+	}
+	return nil
+}
+
+// RefreshToken повторно получает токен авторизации, используя текущие cookies сессии
+func (a *Account) RefreshToken() error {
+	return a.RefreshTokenContext(context.Background())
+}
+
+// RefreshTokenContext повторно получает токен авторизации с учетом контекста. Если обновление
+// уже выполняется в другой горутине, вызов ожидает его результата вместо повторного запроса
+func (a *Account) RefreshTokenContext(ctx context.Context) error {
+	return a.refreshAuth(ctx, false)
+}
+
+// refreshAuth обновляет токен авторизации, а если это не удалось, relogin равен true и известен
+// пароль или CredentialProvider, выполняет вход заново. Одновременные вызовы не запускают
+// повторное обновление, а ожидают результата уже выполняющегося (или отмены своего ctx)
+func (a *Account) refreshAuth(ctx context.Context, relogin bool) error {
+	a.refreshMu.Lock()
+	if flight := a.refreshing; flight != nil {
+		a.refreshMu.Unlock()
+		select {
+		case <-flight.done:
+			return flight.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	flight := &authRefresh{done: make(chan struct{})}
+	a.refreshing = flight
+	a.refreshMu.Unlock()
+
+	flight.err = a.performRefresh(ctx, relogin)
+
+	a.refreshMu.Lock()
+	a.refreshing = nil
+	a.refreshMu.Unlock()
+	close(flight.done)
+	return flight.err
+}
+
+// performRefresh выполняет обновление авторизации для refreshAuth
+func (a *Account) performRefresh(ctx context.Context, relogin bool) error {
+	err := a.fetchAuthToken(ctx)
+	if err == nil {
+		a.setAuthState(AuthStateRefreshed)
+		return nil
+	}
+	a.setAuthState(AuthStateLost)
+
+	if !relogin || (a.Password == "" && a.credentials == nil) {
+		return err
+	}
+	return a.LoginContext(ctx)
+}
+
+// handleSessionExpired обрабатывает потерю сессии, обнаруженную по ответу API: обновляет токен,
+// а если это не удалось и известен пароль, выполняет вход заново. Запрос, на котором обнаружена
+// потеря сессии, не повторяется: возвращается NotAuthorizedError, и вызывающий может повторить операцию
+func (a *Account) handleSessionExpired(ctx context.Context, source string) error {
+	a.setAuthState(AuthStateLost)
+
+	if err := a.refreshAuth(ctx, true); err != nil {
+		return &NotAuthorizedError{
+			Message: fmt.Sprintf("Сессия недействительна: сервер вернул страницу входа, повторная авторизация не удалась: %v", err),
+			Source:  source,
+		}
+	}
+	return &NotAuthorizedError{
+		Message: "Сессия недействительна: сервер вернул страницу входа. Авторизация обновлена, повторите операцию",
+		Source:  source,
+	}
+}
+
+// OnAuthStateChanged устанавливает обработчик, вызываемый при входе, обновлении токена и потере авторизации
+func (a *Account) OnAuthStateChanged(handler AuthStateChangedEventHandler) {
+	a.authMu.Lock()
+	defer a.authMu.Unlock()
+	a.authStateChanged = handler
+}
+
+// setAuthState запоминает состояние авторизации и уведомляет обработчик.
+// Потеря авторизации сообщается только один раз после последнего успешного входа
+func (a *Account) setAuthState(state AuthState) {
+	a.authMu.Lock()
+	if state == AuthStateLost && !a.authorized {
+		a.authMu.Unlock()
+		return
+	}
+	a.authorized = state != AuthStateLost
+	handler := a.authStateChanged
+	a.authMu.Unlock()
+
+	// Обработчик вызывается без блокировки, чтобы он мог обращаться к аккаунту
+	if handler != nil {
+		handler(state)
+	}
+}
+
 // CheckAuthorization проверяет текущую авторизацию клиента
 func (a *Account) CheckAuthorization() (bool, error) {
-	err := a.checkAuthorization(false)
+	return a.CheckAuthorizationContext(context.Background())
+}
+
+// CheckAuthorizationContext проверяет текущую авторизацию клиента с учетом контекста
+func (a *Account) CheckAuthorizationContext(ctx context.Context) (bool, error) {
+	err := a.checkAuthorization(ctx, false)
 	if err != nil {
 		return false, nil
 	}
@@ -187,11 +528,17 @@ func (a *Account) CheckAuthorization() (bool, error) {
 
 // GetDiskUsage получает использование диска для аккаунта
 func (a *Account) GetDiskUsage() (*DiskUsage, error) {
-	return a.getDiskUsageInternal(true)
+	return a.GetDiskUsageContext(context.Background())
+}
+
+// GetDiskUsageContext получает использование диска для аккаунта с учетом контекста.
+// Позволяет прервать запрос, например, при остановке цикла мониторинга
+func (a *Account) GetDiskUsageContext(ctx context.Context) (*DiskUsage, error) {
+	return a.getDiskUsageInternal(ctx, true)
 }
 
 // checkAuthorization проверяет опции авторизации
-func (a *Account) checkAuthorization(baseCheckout bool) error {
+func (a *Account) checkAuthorization(ctx context.Context, baseCheckout bool) error {
 	if a.Email == "" {
 		return &NotAuthorizedError{
 			Message: "Email не определен",
@@ -199,7 +546,8 @@ func (a *Account) checkAuthorization(baseCheckout bool) error {
 		}
 	}
 
-	if a.Password == "" {
+	// Пароль нужен только для входа: аккаунт, созданный по токену, работает без него
+	if a.Password == "" && (baseCheckout || a.getAuthToken() == "") {
 		return &NotAuthorizedError{
 			Message: "Password не определен",
 			Source:  "Password",
@@ -211,11 +559,11 @@ func (a *Account) checkAuthorization(baseCheckout bool) error {
 			return &NotAuthorizedError{Message: "Отсутствуют cookies"}
 		}
 
-		if a.authToken == "" {
+		if a.getAuthToken() == "" {
 			return &NotAuthorizedError{Message: "Отсутствует токен авторизации"}
 		}
 
-		_, err := a.getDiskUsageInternal(false)
+		_, err := a.getDiskUsageInternal(ctx, false)
 		if err != nil {
 			return err
 		}
@@ -225,27 +573,27 @@ func (a *Account) checkAuthorization(baseCheckout bool) error {
 }
 
 // getDiskUsageInternal получает использование диска для аккаунта
-func (a *Account) getDiskUsageInternal(checkAuthorization bool) (*DiskUsage, error) {
+func (a *Account) getDiskUsageInternal(ctx context.Context, checkAuthorization bool) (*DiskUsage, error) {
 	if checkAuthorization {
-		if err := a.checkAuthorization(false); err != nil {
+		if err := a.checkAuthorization(ctx, false); err != nil {
 			return nil, err
 		}
 	}
 
-	diskSpaceURL := fmt.Sprintf(BaseMailRuCloud+DiskSpace, a.Email, a.authToken)
-	req, err := http.NewRequest("GET", diskSpaceURL, nil)
+	diskSpaceURL := fmt.Sprintf(a.cloudURL()+DiskSpace, a.Email, a.getAuthToken())
+	req, err := a.newRequest(ctx, "GET", diskSpaceURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := a.httpClient.Do(req)
+	resp, err := a.do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
+		a.setAuthState(AuthStateLost)
 		return nil, &NotAuthorizedError{Message: "Клиент не авторизован"}
 	}
 
@@ -270,29 +618,33 @@ func (a *Account) getDiskUsageInternal(checkAuthorization bool) (*DiskUsage, err
 }
 
 // getRates получает активированные тарифы
-func (a *Account) getRates() ([]*Rate, error) {
-	if err := a.checkAuthorization(false); err != nil {
+func (a *Account) getRates(ctx context.Context) ([]*Rate, error) {
+	if err := a.checkAuthorization(ctx, false); err != nil {
 		return nil, err
 	}
 
-	ratesURL := fmt.Sprintf(BaseMailRuCloud+RatesURL, a.Email, a.Email, a.authToken)
-	req, err := http.NewRequest("GET", ratesURL, nil)
+	ratesURL := fmt.Sprintf(a.cloudURL()+RatesURL, a.Email, a.Email, a.getAuthToken())
+	req, err := a.newRequest(ctx, "GET", ratesURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := a.httpClient.Do(req)
+	resp, err := a.do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
+	// У новых аккаунтов тарифов может не быть вовсе, и сервер возвращает пустой ответ
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
 	var ratesResp struct {
 		Body []*Rate `json:"body"`
 	}
@@ -315,41 +667,120 @@ func (a *Account) getRates() ([]*Rate, error) {
 // initHttpClient инициализирует HTTP клиент
 func (a *Account) initHttpClient(baseURL string) {
 	// Создаем новый jar, если его нет, или используем существующий
+	a.authMu.Lock()
+	defer a.authMu.Unlock()
+
 	if a.cookies == nil {
 		jar, _ := cookiejar.New(nil)
 		a.cookies = jar
 	}
 
+	if a.transport == nil {
+		a.transport = newDefaultTransport()
+	}
+
 	// Создаем HTTP клиент с jar для cookies
 	a.httpClient = &http.Client{
-		Jar:     a.cookies,
-		Timeout: 0, // Без таймаута
+		Jar:       a.cookies,
+		Transport: a.transport,
+		Timeout:   0, // Без таймаута
 	}
 }
 
+// newRequest создает HTTP запрос со стандартными заголовками библиотеки
+func (a *Account) newRequest(ctx context.Context, method, requestURL string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	if a.acceptLanguage != "" {
+		req.Header.Set("Accept-Language", a.acceptLanguage)
+	}
+
+	for key, values := range a.defaultHeaders {
+		if protectedHeaders[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return req, nil
+}
+
+// setRequestDoer устанавливает функцию выполнения HTTP запросов аккаунта. Если аккаунт используется
+// несколькими клиентами, запросы аккаунта учитываются клиентом, созданным последним
+func (a *Account) setRequestDoer(doer func(req *http.Request) (*http.Response, error)) {
+	a.authMu.Lock()
+	defer a.authMu.Unlock()
+	a.requestDoer = doer
+}
+
+// do выполняет HTTP запрос аккаунта через requestDoer клиента или напрямую через httpClient
+func (a *Account) do(req *http.Request) (*http.Response, error) {
+	a.authMu.RLock()
+	doer := a.requestDoer
+	a.authMu.RUnlock()
+	if doer != nil {
+		return doer(req)
+	}
+	return a.getHttpClient().Do(req)
+}
+
 // getAuthToken возвращает токен авторизации
 func (a *Account) getAuthToken() string {
+	a.authMu.RLock()
+	defer a.authMu.RUnlock()
 	return a.authToken
 }
 
+// setAuthToken сохраняет токен авторизации
+func (a *Account) setAuthToken(token string) {
+	a.authMu.Lock()
+	defer a.authMu.Unlock()
+	a.authToken = token
+}
+
 // getHttpClient возвращает HTTP клиент
 func (a *Account) getHttpClient() *http.Client {
+	a.authMu.RLock()
+	defer a.authMu.RUnlock()
 	return a.httpClient
 }
 
-// deserializeJSON десериализует JSON в объект
+// SetResponseDecoder устанавливает декодер ответов API облака вместо стандартного. Декодер получает
+// тело ответа целиком и должен заполнить target. Позволяет продолжить работу, если Mail.ru изменит
+// формат ответов раньше, чем выйдет обновление библиотеки. nil восстанавливает стандартный декодер
+func (a *Account) SetResponseDecoder(decoder func(data []byte, target interface{}) error) {
+	a.responseDecoder = decoder
+}
+
+// decodeResponse десериализует ответ API облака установленным декодером
+func (a *Account) decodeResponse(data []byte, target interface{}) error {
+	if a.responseDecoder != nil {
+		return a.responseDecoder(data, target)
+	}
+	return deserializeJSON(data, target)
+}
+
+// deserializeJSON десериализует JSON в объект. Сначала ответ разбирается как обертка {"body": ...},
+// при ее отсутствии - как значение без обертки. Если ни один вариант не подходит, возвращается
+// ResponseDecodeError с началом тела ответа
 func deserializeJSON(data []byte, target interface{}) error {
 	var resp struct {
 		Body json.RawMessage `json:"body"`
 	}
-	if err := json.Unmarshal(data, &resp); err != nil {
-		// Если структура не соответствует DefaultResponse, пробуем десериализовать напрямую
-		return json.Unmarshal(data, target)
+	if err := json.Unmarshal(data, &resp); err == nil && len(resp.Body) > 0 {
+		if err := json.Unmarshal(resp.Body, target); err != nil {
+			return newResponseDecodeError(data, err)
+		}
+		return nil
 	}
 
-	if len(resp.Body) == 0 {
-		return json.Unmarshal(data, target)
+	if err := json.Unmarshal(data, target); err != nil {
+		return newResponseDecodeError(data, err)
 	}
-
-	return json.Unmarshal(resp.Body, target)
+	return nil
 }
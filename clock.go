@@ -0,0 +1,65 @@
+package mailrucloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// clockOffsetTTL время, в течение которого измеренное расхождение часов считается актуальным
+const clockOffsetTTL = 10 * time.Minute
+
+// ServerTime возвращает текущее время сервера облака, вычисленное по расхождению часов (ClockOffset)
+func (c *CloudClient) ServerTime() (time.Time, error) {
+	offset, err := c.ClockOffset()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(offset).UTC(), nil
+}
+
+// ClockOffset возвращает расхождение часов сервера облака и локальной машины (положительное, если часы
+// сервера спешат). Измеряется по заголовку Date ответа на легкий HEAD запрос с точностью до секунды
+// и кешируется на clockOffsetTTL. Значение можно использовать для поправки при сравнении времени
+// модификации локальных и удаленных файлов, например в WithClockSkewTolerance
+func (c *CloudClient) ClockOffset() (time.Duration, error) {
+	c.clockMu.Lock()
+	defer c.clockMu.Unlock()
+
+	if !c.clockOffsetTime.IsZero() && time.Since(c.clockOffsetTime) < clockOffsetTTL {
+		return c.clockOffset, nil
+	}
+
+	offset, err := c.measureClockOffset()
+	if err != nil {
+		return 0, err
+	}
+	c.clockOffset = offset
+	c.clockOffsetTime = time.Now()
+	return offset, nil
+}
+
+// measureClockOffset измеряет расхождение часов по заголовку Date, относя время сервера к середине запроса
+func (c *CloudClient) measureClockOffset() (time.Duration, error) {
+	req, err := c.newRequest(context.Background(), "HEAD", c.Account.cloudURL()+"/", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	end := time.Now()
+	drainAndClose(resp.Body)
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("сервер не вернул корректный заголовок Date: %w", err)
+	}
+
+	local := start.Add(end.Sub(start) / 2)
+	return serverTime.Sub(local), nil
+}
@@ -2,7 +2,10 @@ package mailrucloud
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,7 +13,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -26,10 +31,74 @@ type CloudClient struct {
 	// cancelToken токен отмены асинхронных задач
 	cancelToken context.CancelFunc
 	cancelCtx   context.Context
+	// downloadResume включает возобновление прерванного скачивания
+	downloadResume bool
+	// copyBufferPool пул буферов копирования данных
+	copyBufferPool *sync.Pool
+	// uploadRetries количество повторных попыток загрузки на шард
+	uploadRetries int
+	// disableFolderAutoRefresh отключает автоматическое обновление списка элементов папок
+	disableFolderAutoRefresh bool
+	// progressSmoothing коэффициент сглаживания оценки скорости передачи
+	progressSmoothing float64
+	// chunkVerification включает проверку хеша загруженного содержимого
+	chunkVerification bool
+	// uploadLimiter ограничитель скорости загрузки
+	uploadLimiter bandwidthLimiter
+	// downloadLimiter ограничитель скорости скачивания
+	downloadLimiter bandwidthLimiter
+	// transfersMu защищает признак остановки при регистрации новых передач
+	transfersMu sync.Mutex
+	// shuttingDown признак остановки клиента через Shutdown
+	shuttingDown bool
+	// transfers выполняющиеся загрузки и скачивания
+	transfers sync.WaitGroup
+	// clockMu защищает кешированное расхождение часов
+	clockMu sync.Mutex
+	// clockOffset расхождение часов сервера и локальной машины
+	clockOffset time.Duration
+	// clockOffsetTime время измерения clockOffset (нулевое, если измерения не было)
+	clockOffsetTime time.Time
+	// requestSlots семафор одновременных HTTP запросов (nil - без ограничения)
+	requestSlots chan struct{}
+	// readOnly запрещает запросы, изменяющие облако
+	readOnly bool
+	// stats счетчики статистики передачи
+	stats transferCounters
+	// metrics получатель метрик запросов и передач (nil - метрики не собираются)
+	metrics MetricsCollector
+}
+
+// protectedHeaders заголовки, которые не могут быть переопределены через SetDefaultHeaders
+var protectedHeaders = map[string]bool{
+	"Cookie":         true,
+	"Content-Type":   true,
+	"Content-Length": true,
+	"Host":           true,
+	"Range":          true,
 }
 
 // NewCloudClient создает новый экземпляр CloudClient
-func NewCloudClient(account *Account) (*CloudClient, error) {
+func NewCloudClient(account *Account, opts ...CloudClientOption) (*CloudClient, error) {
+	client, err := NewCloudClientNoCheck(account, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверка авторизации
+	if _, err := account.CheckAuthorization(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// NewCloudClientNoCheck создает CloudClient для аккаунта без проверки авторизации запросом
+// использования диска. Подходит для создания нескольких клиентов (например, рабочих горутин)
+// из одного аккаунта, авторизация которого уже проверена через Login, NewCloudClient или
+// CheckAuthorization. Если сессия аккаунта истекла, ошибка авторизации будет возвращена
+// первой операцией клиента
+func NewCloudClientNoCheck(account *Account, opts ...CloudClientOption) (*CloudClient, error) {
 	if account == nil {
 		return nil, fmt.Errorf("account не может быть nil")
 	}
@@ -40,27 +109,61 @@ func NewCloudClient(account *Account) (*CloudClient, error) {
 		cancelToken: cancel,
 		cancelCtx:   ctx,
 	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	account.setRequestDoer(client.send)
+	return client, nil
+}
+
+// NewCloudClientWithToken создает CloudClient по токену авторизации и cookies сессии, полученным
+// отдельно (Account.Token и Account.Cookies), без входа по паролю. Токен проверяется при создании;
+// если он недействителен, возвращается NotAuthorizedError
+func NewCloudClientWithToken(email, token string, cookies []*http.Cookie, opts ...CloudClientOption) (*CloudClient, error) {
+	if token == "" {
+		return nil, &NotAuthorizedError{Message: "Токен авторизации не может быть пустым", Source: "token"}
+	}
+	return newCloudClientWithTokenAccount(NewAccountWithToken(email, token, cookies), opts...)
+}
 
-	// Проверка авторизации
-	if _, err := account.CheckAuthorization(); err != nil {
+// newCloudClientWithTokenAccount проверяет токен аккаунта, созданного через NewAccountWithToken, и создает клиент
+func newCloudClientWithTokenAccount(account *Account, opts ...CloudClientOption) (*CloudClient, error) {
+	ctx := context.Background()
+	if err := account.checkAuthorization(ctx, false); err != nil {
+		var authErr *NotAuthorizedError
+		if errors.As(err, &authErr) {
+			return nil, &NotAuthorizedError{Message: "Токен авторизации недействителен: " + authErr.Message, Source: "token"}
+		}
 		return nil, err
 	}
 
-	return client, nil
+	_ = account.loadActivatedRates(ctx)
+	return NewCloudClient(account, opts...)
 }
 
 // NewCloudClientWithCredentials создает новый экземпляр CloudClient с учетными данными
-func NewCloudClientWithCredentials(email, password string) (*CloudClient, error) {
+func NewCloudClientWithCredentials(email, password string, opts ...CloudClientOption) (*CloudClient, error) {
 	account := NewAccount(email, password)
 	if err := account.Login(); err != nil {
 		return nil, err
 	}
-	return NewCloudClient(account)
+	return NewCloudClient(account, opts...)
+}
+
+// OpenRoot выполняет вход с учетными данными, создает CloudClient и возвращает корневую папку облака.
+// Ошибки авторизации возвращаются без изменений. Сокращает код простых сценариев, которым достаточно
+// методов папки
+func OpenRoot(email, password string, opts ...CloudClientOption) (*Folder, error) {
+	client, err := NewCloudClientWithCredentials(email, password, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetFolder("/")
 }
 
 // GetFileOneTimeDirectLink предоставляет одноразовую анонимную прямую ссылку для скачивания файла
 func (c *CloudClient) GetFileOneTimeDirectLink(publicLink string) (string, error) {
-	if publicLink == "" || !strings.HasPrefix(publicLink, PublicLink) {
+	if publicLink == "" || !strings.HasPrefix(publicLink, c.Account.publicLinkPrefix()) {
 		return "", &CloudClientError{
 			Message:   "Некорректная публичная ссылка",
 			ErrorCode: ErrorCodePathNotExists,
@@ -80,18 +183,16 @@ func (c *CloudClient) GetFileOneTimeDirectLink(publicLink string) (string, error
 		formData.Set(k, fmt.Sprintf("%v", v))
 	}
 
-	req, err := http.NewRequest("POST", BaseMailRuCloud+DownloadTokenURL, strings.NewReader(formData.Encode()))
+	req, err := c.newFormRequest(context.Background(), c.Account.cloudURL()+DownloadTokenURL, formData)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -99,71 +200,234 @@ func (c *CloudClient) GetFileOneTimeDirectLink(publicLink string) (string, error
 	}
 
 	var tokenResp AuthToken
-	if err := deserializeJSON(body, &tokenResp); err != nil {
+	if err := c.Account.decodeResponse(body, &tokenResp); err != nil {
+		return "", err
+	}
+
+	shardURL, err := c.getShardURL("WeblinkGet", func(shards *ShardsList) []*ShardInfo { return shards.WeblinkGet })
+	if err != nil {
+		return "", err
+	}
+
+	filePath := strings.TrimPrefix(publicLink, c.Account.publicLinkPrefix())
+	return fmt.Sprintf("%s/%s?key=%s", shardURL, filePath, tokenResp.Token), nil
+}
+
+// GetFileOneTimeDirectLinkAs предоставляет одноразовую анонимную прямую ссылку для скачивания файла
+// под именем downloadName: имя передается шарду в параметре filename и используется в заголовке
+// Content-Disposition ответа. Имя не должно содержать разделители пути и управляющие символы
+func (c *CloudClient) GetFileOneTimeDirectLinkAs(publicLink, downloadName string) (string, error) {
+	if err := validateDownloadName(downloadName); err != nil {
 		return "", err
 	}
 
-	shards, err := c.getShardsInfo()
+	link, err := c.GetFileOneTimeDirectLink(publicLink)
 	if err != nil {
 		return "", err
 	}
+	return link + "&filename=" + url.QueryEscape(downloadName), nil
+}
+
+// validateDownloadName проверяет имя файла для заголовка Content-Disposition
+func validateDownloadName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return &CloudClientError{
+			Message: "Имя файла для скачивания не может быть пустым",
+			Source:  "downloadName",
+		}
+	}
+	if strings.ContainsFunc(name, func(r rune) bool { return r == '/' || r == '\\' || r < 0x20 || r == 0x7f }) {
+		return &CloudClientError{
+			Message: "Имя файла для скачивания не должно содержать разделители пути и управляющие символы",
+			Source:  "downloadName",
+		}
+	}
+	return nil
+}
 
-	if len(shards.WeblinkGet) == 0 {
-		return "", fmt.Errorf("шарды WeblinkGet не найдены")
+// OpenPublicLink открывает опубликованный файл по публичной ссылке как поток с поддержкой перемещения.
+// Перемещение выполняется Range запросами к шарду WeblinkGet по одноразовой ссылке, полученной через
+// GetFileOneTimeDirectLink. Токен такой ссылки может быть действителен только в рамках одной сессии,
+// поэтому поток следует использовать сразу после открытия и не хранить длительное время.
+// Если сервер не сообщил размер файла, возвращается -1. Поток необходимо закрыть: до этого Shutdown
+// ожидает завершения передачи
+func (c *CloudClient) OpenPublicLink(publicLink string) (io.ReadSeekCloser, int64, error) {
+	if err := c.beginTransfer(); err != nil {
+		return nil, 0, err
 	}
 
-	shardURL := shards.WeblinkGet[0].URL
-	filePath := strings.Replace(publicLink, PublicLink, "", 1)
-	return fmt.Sprintf("%s/%s?key=%s", shardURL, filePath, tokenResp.Token), nil
+	directLink, err := c.GetFileOneTimeDirectLink(publicLink)
+	if err != nil {
+		c.transfers.Done()
+		return nil, 0, err
+	}
+
+	reader, err := newRangeReader(c.cancelCtx, c, directLink)
+	if err != nil {
+		c.transfers.Done()
+		return nil, 0, err
+	}
+	reader.done = c.transfers.Done
+	return reader, reader.size, nil
 }
 
 // Publish публикует файл или папку
 func (c *CloudClient) Publish(sourceFullPath string) (*CloudStructureEntryBase, error) {
-	return c.publishUnpublishInternal(sourceFullPath, true)
+	return c.publishUnpublishInternal(sourceFullPath, true, nil)
+}
+
+// PublishWithOptions публикует файл или папку с дополнительными параметрами публичной ссылки.
+// Повторный вызов для уже опубликованного элемента обновляет параметры существующей ссылки
+func (c *CloudClient) PublishWithOptions(sourceFullPath string, options PublishOptions) (*CloudStructureEntryBase, error) {
+	if options.MaxDownloads < 0 {
+		return nil, &CloudClientError{
+			Message:   "Лимит скачиваний не может быть отрицательным",
+			Source:    "options.MaxDownloads",
+			ErrorCode: ErrorCodeNotSupportedOperation,
+		}
+	}
+	return c.publishUnpublishInternal(sourceFullPath, true, &options)
 }
 
 // Unpublish отменяет публикацию файла или папки
 func (c *CloudClient) Unpublish(publicLink string) (*CloudStructureEntryBase, error) {
-	return c.publishUnpublishInternal(publicLink, false)
+	return c.publishUnpublishInternal(publicLink, false, nil)
 }
 
 // RestoreFileFromHistory восстанавливает файл из истории
 func (c *CloudClient) RestoreFileFromHistory(sourceFullPath string, historyRevision int64, rewriteExisting bool, newFileName string) (*File, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(sourceFullPath)
+	}
+	if err := c.checkRestoreAllowed(historyRevision); err != nil {
+		return nil, err
+	}
+
+	histories, err := c.GetFileHistory(sourceFullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := findHistoryRevision(histories, historyRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.restoreFromHistory(sourceFullPath, history, rewriteExisting, newFileName)
+}
+
+// RestoreFileFromHistorySafely восстанавливает файл из истории на месте текущей версии так, чтобы
+// восстановление было обратимым. Возвращается номер ревизии текущей версии до восстановления,
+// по которому ее можно вернуть через RestoreFileFromHistory. Если snapshot равен true, текущая версия
+// дополнительно сохраняется рядом как отдельный файл (без передачи данных, по хешу) и ее путь
+// возвращается в RestoreResult.BackupPath
+func (c *CloudClient) RestoreFileFromHistorySafely(sourceFullPath string, historyRevision int64, snapshot bool) (*RestoreResult, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(sourceFullPath)
+	}
+	if err := c.checkRestoreAllowed(historyRevision); err != nil {
+		return nil, err
+	}
+
+	histories, err := c.GetFileHistory(sourceFullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := findHistoryRevision(histories, historyRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkHistoryHash(history); err != nil {
+		return nil, err
+	}
+	current := latestHistoryRevision(histories)
+	result := &RestoreResult{PreviousRevision: current.Revision}
+
+	if snapshot {
+		if err := checkHistoryHash(current); err != nil {
+			return nil, err
+		}
+		sourceFullPath = c.getPathStartEndSlash(sourceFullPath, true, false)
+		name := filepath.Base(sourceFullPath)
+		extension := filepath.Ext(name)
+		backupName := fmt.Sprintf("%s (rev %d)%s", strings.TrimSuffix(name, extension), current.Revision, extension)
+
+		backup, err := c.createFileOrFolder(true, c.getParentCloudPath(sourceFullPath)+backupName, current.Hash, current.SizeBytes, ConflictModeRename)
+		if err != nil {
+			return nil, err
+		}
+		result.BackupPath = backup.NewPath
+	}
+
+	result.File, err = c.restoreFromHistory(sourceFullPath, history, true, "")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkRestoreAllowed проверяет, что восстановление из истории доступно
+func (c *CloudClient) checkRestoreAllowed(historyRevision int64) error {
 	if historyRevision <= 0 {
-		return nil, &CloudClientError{
+		return &CloudClientError{
 			Message:   "Ревизия должна быть больше 0",
 			ErrorCode: ErrorCodeHistoryNotExists,
 		}
 	}
 
 	if c.Account.Has2GBUploadSizeLimit() {
-		return nil, &CloudClientError{
+		return &CloudClientError{
 			Message:   "Текущая операция не поддерживается для вашего аккаунта. Пожалуйста, обновите тарифный план",
 			ErrorCode: ErrorCodeNotSupportedOperation,
 		}
 	}
+	return nil
+}
 
-	histories, err := c.GetFileHistory(sourceFullPath)
-	if err != nil {
-		return nil, err
+// findHistoryRevision ищет запись истории по номеру ревизии
+func findHistoryRevision(histories []*History, historyRevision int64) (*History, error) {
+	for _, h := range histories {
+		if h.Revision == historyRevision {
+			return h, nil
+		}
+	}
+
+	return nil, &CloudClientError{
+		Message:   "История не существует по указанному номеру ревизии",
+		Source:    "historyRevision",
+		ErrorCode: ErrorCodeHistoryNotExists,
 	}
+}
 
-	var history *History
+// latestHistoryRevision возвращает запись истории с наибольшим номером ревизии, то есть текущую
+// версию файла. Сервер не гарантирует порядок записей истории
+func latestHistoryRevision(histories []*History) *History {
+	var latest *History
 	for _, h := range histories {
-		if h.Revision == historyRevision {
-			history = h
-			break
+		if latest == nil || h.Revision > latest.Revision {
+			latest = h
 		}
 	}
+	return latest
+}
 
-	if history == nil {
-		return nil, &CloudClientError{
-			Message:   "История не существует по указанному номеру ревизии",
+// checkHistoryHash возвращает ошибку с кодом ErrorCodeHistoryNotExists, если сервер не вернул хеш
+// содержимого ревизии: без него нельзя ни скачать, ни восстановить ревизию
+func checkHistoryHash(history *History) error {
+	if history.Hash == "" {
+		return &CloudClientError{
+			Message:   "Для указанной ревизии сервер не вернул хеш содержимого",
 			Source:    "historyRevision",
 			ErrorCode: ErrorCodeHistoryNotExists,
 		}
 	}
+	return nil
+}
 
+// restoreFromHistory создает файл из записи истории
+func (c *CloudClient) restoreFromHistory(sourceFullPath string, history *History, rewriteExisting bool, newFileName string) (*File, error) {
 	originalFileName := filepath.Base(sourceFullPath)
 	extension := filepath.Ext(originalFileName)
 	if newFileName == "" {
@@ -178,16 +442,29 @@ func (c *CloudClient) RestoreFileFromHistory(sourceFullPath string, historyRevis
 		newFullPath = parentPath + newFileName
 	}
 
-	created, err := c.createFileOrFolder(true, newFullPath, history.Hash, history.SizeBytes, rewriteExisting)
+	conflict := ConflictModeRename
+	if rewriteExisting {
+		conflict = ConflictModeRewrite
+	}
+
+	created, err := c.createFileOrFolder(true, newFullPath, history.Hash, history.SizeBytes, conflict)
 	if err != nil {
 		return nil, err
 	}
 
+	// Сервер может изменить имя и метаданные восстановленного файла, поэтому они запрашиваются повторно.
+	// Если запрос не удался, используются данные записи истории
+	if entry, err := c.getEntry(created.NewPath); err == nil {
+		return newFileFromEntry(entry, c), nil
+	}
+
 	return &File{
 		CloudStructureEntryBase: CloudStructureEntryBase{
 			FullPath: created.NewPath,
 			Name:     created.NewName,
 			Size:     history.Size,
+			account:  c.Account,
+			client:   c,
 		},
 		Hash:                history.Hash,
 		LastModifiedTimeUTC: history.LastModifiedTimeUTC,
@@ -216,19 +493,17 @@ func (c *CloudClient) GetFileHistory(sourceFullPath string) ([]*History, error)
 		formData.Set(k, fmt.Sprintf("%v", v))
 	}
 
-	historyURL := fmt.Sprintf(BaseMailRuCloud+HistoryURL, sourceFullPath, c.Account.Email, c.Account.Email, c.Account.getAuthToken())
-	req, err := http.NewRequest("POST", historyURL, strings.NewReader(formData.Encode()))
+	historyURL := fmt.Sprintf(c.Account.cloudURL()+HistoryURL, sourceFullPath, c.Account.Email, c.Account.Email, c.Account.getAuthToken())
+	req, err := c.newFormRequest(context.Background(), historyURL, formData)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	if resp.StatusCode == http.StatusNotFound {
 		return nil, &CloudClientError{
@@ -244,7 +519,7 @@ func (c *CloudClient) GetFileHistory(sourceFullPath string) ([]*History, error)
 	}
 
 	var historyList []*History
-	if err := deserializeJSON(body, &historyList); err != nil {
+	if err := c.Account.decodeResponse(body, &historyList); err != nil {
 		return nil, err
 	}
 
@@ -259,8 +534,17 @@ func (c *CloudClient) GetFileHistory(sourceFullPath string) ([]*History, error)
 	return historyList, nil
 }
 
-// Remove удаляет файл или папку
+// Remove удаляет файл или папку. Сервер не удаляет элемент безвозвратно, а перемещает его в корзину,
+// откуда его можно восстановить через RestoreFromTrash. Эквивалентен MoveToTrash
 func (c *CloudClient) Remove(sourceFullPath string) error {
+	return c.MoveToTrash(sourceFullPath)
+}
+
+// MoveToTrash перемещает файл или папку в корзину
+func (c *CloudClient) MoveToTrash(sourceFullPath string) error {
+	if c.readOnly {
+		return newReadOnlyError(sourceFullPath)
+	}
 	if sourceFullPath == "" {
 		return &CloudClientError{
 			Message:   "Путь не может быть пустым",
@@ -280,24 +564,42 @@ func (c *CloudClient) Remove(sourceFullPath string) error {
 		formData.Set(k, fmt.Sprintf("%v", v))
 	}
 
-	req, err := http.NewRequest("POST", BaseMailRuCloud+Remove, strings.NewReader(formData.Encode()))
+	req, err := c.newFormRequest(context.Background(), c.Account.cloudURL()+Remove, formData)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
+	if resp.StatusCode == http.StatusNotFound {
+		return &CloudClientError{
+			Message:   "Элемент по указанному пути не существует",
+			Source:    sourceFullPath,
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("перемещение в корзину не удалось: статус %d", resp.StatusCode)
+	}
 	return nil
 }
 
 // Rename переименовывает элемент структуры облака
 func (c *CloudClient) Rename(sourceFullPath, name string) (*CloudStructureEntryBase, error) {
+	return c.RenameWithOptions(sourceFullPath, name, RenameOptions{})
+}
+
+// RenameWithOptions переименовывает файл или папку с дополнительными параметрами. Публичная ссылка
+// привязана к элементу, а не к пути, поэтому для опубликованного элемента ее актуальное значение
+// после переименования запрашивается у сервера и сохраняется в результате
+func (c *CloudClient) RenameWithOptions(sourceFullPath, name string, options RenameOptions) (*CloudStructureEntryBase, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(sourceFullPath)
+	}
 	if sourceFullPath == "" {
 		return nil, &CloudClientError{
 			Message:   "Путь не может быть пустым",
@@ -311,6 +613,9 @@ func (c *CloudClient) Rename(sourceFullPath, name string) (*CloudStructureEntryB
 			ErrorCode: ErrorCodePathNotExists,
 		}
 	}
+	if err := IsValidCloudName(name); err != nil {
+		return nil, err
+	}
 
 	if err := c.checkAuthorization(); err != nil {
 		return nil, err
@@ -335,18 +640,16 @@ func (c *CloudClient) Rename(sourceFullPath, name string) (*CloudStructureEntryB
 		formData.Set(k, fmt.Sprintf("%v", v))
 	}
 
-	req, err := http.NewRequest("POST", BaseMailRuCloud+Rename, strings.NewReader(formData.Encode()))
+	req, err := c.newFormRequest(context.Background(), c.Account.cloudURL()+Rename, formData)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -354,30 +657,123 @@ func (c *CloudClient) Rename(sourceFullPath, name string) (*CloudStructureEntryB
 	}
 
 	var newPath string
-	if err := deserializeJSON(body, &newPath); err != nil {
+	if err := c.Account.decodeResponse(body, &newPath); err != nil {
 		return nil, err
 	}
 
-	newName := filepath.Base(newPath)
+	publicLink := item.PublicLink
 	item.PublicLink = ""
 	item.FullPath = newPath
-	item.Name = newName
+	item.Name = filepath.Base(newPath)
+	if publicLink == "" {
+		return item, nil
+	}
 
+	if entry, err := c.getEntry(newPath); err == nil {
+		item.PublicLink = c.Account.buildPublicLink(entry.Weblink)
+	}
+	if item.PublicLink == "" && options.Republish {
+		published, err := c.Publish(newPath)
+		if err != nil {
+			return nil, err
+		}
+		item.PublicLink = published.PublicLink
+	}
 	return item, nil
 }
 
 // Copy копирует элемент структуры облака
 func (c *CloudClient) Copy(sourceFullPath, destFolderPath string) (*CloudStructureEntryBase, error) {
-	return c.moveOrCopyInternal(sourceFullPath, destFolderPath, false)
+	return c.moveOrCopyInternal(sourceFullPath, destFolderPath, false, MoveOptions{})
+}
+
+// CopyWithOptions копирует элемент структуры облака с дополнительными параметрами
+func (c *CloudClient) CopyWithOptions(sourceFullPath, destFolderPath string, options MoveOptions) (*CloudStructureEntryBase, error) {
+	return c.moveOrCopyInternal(sourceFullPath, destFolderPath, false, options)
 }
 
 // Move перемещает элемент структуры облака
 func (c *CloudClient) Move(sourceFullPath, destFolderPath string) (*CloudStructureEntryBase, error) {
-	return c.moveOrCopyInternal(sourceFullPath, destFolderPath, true)
+	return c.moveOrCopyInternal(sourceFullPath, destFolderPath, true, MoveOptions{})
+}
+
+// MoveWithOptions перемещает элемент структуры облака с дополнительными параметрами, например
+// с созданием папки назначения (MoveOptions.CreateDest)
+func (c *CloudClient) MoveWithOptions(sourceFullPath, destFolderPath string, options MoveOptions) (*CloudStructureEntryBase, error) {
+	return c.moveOrCopyInternal(sourceFullPath, destFolderPath, true, options)
+}
+
+// MoveRename перемещает элемент структуры облака в папку назначения и переименовывает его.
+// API облака не поддерживает перемещение с новым именем одним запросом, поэтому выполняется
+// перемещение, а затем переименование. Если переименование не удалось, элемент возвращается
+// в исходную папку
+func (c *CloudClient) MoveRename(sourceFullPath, destFolderPath, newName string) (*CloudStructureEntryBase, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(sourceFullPath)
+	}
+	if newName == "" {
+		return nil, &CloudClientError{
+			Message:   "Имя не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+	// Имя проверяется до перемещения, чтобы не откатывать перемещение из-за заведомо недопустимого имени
+	if err := IsValidCloudName(newName); err != nil {
+		return nil, err
+	}
+
+	sourceFullPath = c.getPathStartEndSlash(sourceFullPath, true, false)
+	originalFolderPath := c.getParentCloudPath(sourceFullPath)
+
+	moved, err := c.Move(sourceFullPath, destFolderPath)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed, err := c.Rename(moved.FullPath, newName)
+	if err != nil {
+		if _, rollbackErr := c.Move(moved.FullPath, originalFolderPath); rollbackErr != nil {
+			return nil, fmt.Errorf("переименование не удалось: %w; откат перемещения не удался: %v", err, rollbackErr)
+		}
+		return nil, err
+	}
+
+	return renamed, nil
 }
 
-// CreateFolder создает все директории и поддиректории по указанному пути, если они еще не существуют
+// CreateFolder создает все директории и поддиректории по указанному пути, если они еще не существуют.
+// Если папка с таким путем уже существует, создается новая папка с измененным именем (например,
+// "folder (1)"), в том числе при одновременном создании одного пути несколькими клиентами;
+// для получения существующей папки используйте EnsureFolder
 func (c *CloudClient) CreateFolder(fullFolderPath string) (*Folder, error) {
+	return c.CreateFolderWithConflict(fullFolderPath, ConflictModeRename)
+}
+
+const (
+	// ensureFolderRetries количество повторных попыток EnsureFolder после ошибки создания,
+	// если папка еще не появилась
+	ensureFolderRetries = 3
+	// ensureFolderRetryInterval базовый интервал между попытками EnsureFolder
+	ensureFolderRetryInterval = 100 * time.Millisecond
+)
+
+// EnsureFolder возвращает папку по указанному пути, создавая ее вместе с недостающими родительскими
+// папками, только если она еще не существует. В отличие от CreateFolder, повторный вызов не создает
+// папку с измененным именем, поэтому метод подходит для синхронизации. Метод безопасен при
+// одновременном создании одного пути из нескольких горутин или процессов: после ошибки создания
+// существование папки проверяется повторно, и возвращается папка, созданная другим клиентом.
+// Если по пути находится файл, возвращается ошибка
+func (c *CloudClient) EnsureFolder(fullFolderPath string) (*Folder, error) {
+	return c.CreateFolderWithConflict(fullFolderPath, ConflictModeSkip)
+}
+
+// CreateFolderWithConflict создает папку по указанному пути с заданным поведением при конфликте имен:
+// ConflictModeRename создает папку с измененным именем, ConflictModeStrict возвращает ошибку,
+// ConflictModeRewrite передает серверу режим перезаписи, ConflictModeSkip возвращает существующую папку
+func (c *CloudClient) CreateFolderWithConflict(fullFolderPath string, conflict ConflictMode) (*Folder, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(fullFolderPath)
+	}
 	if fullFolderPath == "" {
 		return nil, &CloudClientError{
 			Message:   "Путь не может быть пустым",
@@ -390,49 +786,106 @@ func (c *CloudClient) CreateFolder(fullFolderPath string) (*Folder, error) {
 	}
 
 	fullFolderPath = c.getPathStartEndSlash(fullFolderPath, true, true)
-	createdFolder, err := c.createFileOrFolder(false, fullFolderPath, "", 0, false)
-	if err != nil {
+	if err := validateCloudPath(fullFolderPath); err != nil {
 		return nil, err
 	}
+	for attempt := 0; ; attempt++ {
+		createdFolder, createErr := c.createFileOrFolder(false, fullFolderPath, "", 0, conflict)
+		if createErr == nil {
+			return &Folder{
+				CloudStructureEntryBase: CloudStructureEntryBase{
+					Name:     createdFolder.NewName,
+					FullPath: createdFolder.NewPath,
+					account:  c.Account,
+					client:   c,
+				},
+			}, nil
+		}
+		if conflict != ConflictModeSkip || hasErrorCode(createErr, ErrorCodeInsufficientStorage) {
+			return nil, createErr
+		}
 
-	return &Folder{
-		CloudStructureEntryBase: CloudStructureEntryBase{
-			Name:     createdFolder.NewName,
-			FullPath: createdFolder.NewPath,
-			account:  c.Account,
-			client:   c,
-		},
-	}, nil
+		// Ошибка может быть вызвана одновременным созданием той же папки другим клиентом,
+		// поэтому проверяется, существует ли папка теперь
+		entry, err := c.getEntry(strings.TrimSuffix(fullFolderPath, "/"))
+		if err == nil {
+			if !entry.IsFolder() {
+				return nil, &CloudClientError{
+					Message: "По указанному пути уже существует файл",
+					Source:  fullFolderPath,
+				}
+			}
+			return newFolderFromEntry(entry, c), nil
+		}
+		if attempt >= ensureFolderRetries {
+			c.logGiveUp("ensure_folder", fullFolderPath, attempt+1, createErr)
+			return nil, createErr
+		}
+		c.logRetry("ensure_folder", fullFolderPath, attempt+1, createErr)
+		// Папка, созданная другим клиентом, может появиться в метаданных не сразу
+		time.Sleep(ensureFolderRetryInterval * time.Duration(attempt+1))
+	}
 }
 
-// GetFolder получает информацию о корневой папке, включая список файлов и папок
+// GetFolder получает информацию о папке (по умолчанию корневой), включая список файлов и папок.
+// Для существующей пустой папки возвращается папка с пустым списком Items. Если папка не существует,
+// возвращается CloudClientError с кодом ErrorCodePathNotExists
 func (c *CloudClient) GetFolder(fullPath ...string) (*Folder, error) {
+	path := ""
+	if len(fullPath) > 0 {
+		path = fullPath[0]
+	}
+	return c.GetFolderContext(context.Background(), path)
+}
+
+// GetFolderContext получает папку по полному пути с учетом контекста
+func (c *CloudClient) GetFolderContext(ctx context.Context, fullPath string) (*Folder, error) {
 	if err := c.checkAuthorization(); err != nil {
 		return nil, err
 	}
 
-	path := ""
-	if len(fullPath) > 0 {
-		path = fullPath[0]
+	entry, err := c.getFolderEntry(ctx, fullPath, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	folder := newFolderFromEntry(entry, c)
+	if folder.Items == nil {
+		folder.Items = []*CloudStructureEntry{}
 	}
+	return folder, nil
+}
 
-	path = c.getPathStartEndSlash(path, true, true)
-	itemsListURL := fmt.Sprintf(BaseMailRuCloud+ItemsList, c.Account.getAuthToken(), path)
+// getFolderEntry получает запись папки со списком элементов. Если limit больше 0, список
+// ограничивается limit элементами, начиная с offset
+func (c *CloudClient) getFolderEntry(ctx context.Context, fullPath string, offset, limit int) (*CloudStructureEntry, error) {
+	path := c.getPathStartEndSlash(fullPath, true, true)
+	itemsListURL := fmt.Sprintf(c.Account.cloudURL()+ItemsList, c.Account.getAuthToken(), url.QueryEscape(path))
+	if limit > 0 {
+		itemsListURL += fmt.Sprintf("&offset=%d&limit=%d", offset, limit)
+	}
 
-	req, err := http.NewRequest("GET", itemsListURL, nil)
+	req, err := c.newRequest(ctx, "GET", itemsListURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &CloudClientError{
+			Message:   "Папка по указанному пути не существует",
+			Source:    path,
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, nil
+		return nil, fmt.Errorf("получение папки не удалось: статус %d", resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -441,54 +894,503 @@ func (c *CloudClient) GetFolder(fullPath ...string) (*Folder, error) {
 	}
 
 	var deserialized CloudStructureEntry
-	if err := deserializeJSON(body, &deserialized); err != nil {
+	if err := c.Account.decodeResponse(body, &deserialized); err != nil {
 		return nil, err
 	}
+	return &deserialized, nil
+}
 
-	publicLink := ""
-	if deserialized.Weblink != "" {
-		publicLink = PublicLink + deserialized.Weblink
-	}
-
-	return &Folder{
-		CloudStructureEntryBase: CloudStructureEntryBase{
-			FilesCount:   deserialized.Count.Files,
-			FoldersCount: deserialized.Count.Folders,
-			FullPath:     deserialized.Home,
-			Name:         deserialized.Name,
-			PublicLink:   publicLink,
-			Size:         NewSize(deserialized.Size),
-			account:      c.Account,
-			client:       c,
-		},
-		Items: deserialized.List,
-	}, nil
+// GetFolderOnlyFiles получает папку, в списке Items которой оставлены только файлы.
+// API папок не поддерживает фильтрацию по типу, поэтому список фильтруется на стороне клиента;
+// автоматическое обновление папки (GetFiles, GetFolders) загружает полный список
+func (c *CloudClient) GetFolderOnlyFiles(fullPath string) (*Folder, error) {
+	return c.getFolderFiltered(fullPath, false)
 }
 
-// checkAuthorization проверяет авторизацию
-func (c *CloudClient) checkAuthorization() error {
-	_, err := c.Account.CheckAuthorization()
-	return err
+// GetFolderOnlyFolders получает папку, в списке Items которой оставлены только вложенные папки,
+// например для выбора папки назначения. Фильтрация выполняется так же, как в GetFolderOnlyFiles
+func (c *CloudClient) GetFolderOnlyFolders(fullPath string) (*Folder, error) {
+	return c.getFolderFiltered(fullPath, true)
 }
 
-// getShardsInfo получает информацию о шардах
-func (c *CloudClient) getShardsInfo() (*ShardsList, error) {
-	if err := c.checkAuthorization(); err != nil {
+// getFolderFiltered получает папку и оставляет в списке Items только папки или только файлы
+func (c *CloudClient) getFolderFiltered(fullPath string, folders bool) (*Folder, error) {
+	folder, err := c.GetFolder(fullPath)
+	if err != nil {
 		return nil, err
 	}
 
-	dispatcherURL := fmt.Sprintf(BaseMailRuCloud+Dispatcher, c.Account.getAuthToken())
-	req, err := http.NewRequest("GET", dispatcherURL, nil)
-	if err != nil {
-		return nil, err
+	items := make([]*CloudStructureEntry, 0, len(folder.Items))
+	for _, item := range folder.Items {
+		if item.IsFolder() == folders {
+			items = append(items, item)
+		}
 	}
-	req.Header.Set("User-Agent", UserAgent)
+	folder.Items = items
+	return folder, nil
+}
 
-	resp, err := c.Account.getHttpClient().Do(req)
+// Stat получает метаданные файла или папки по полному пути без получения списка родительской папки.
+// Если элемент не существует, возвращается CloudClientError с кодом ErrorCodePathNotExists
+func (c *CloudClient) Stat(fullPath string) (*CloudStructureEntry, error) {
+	return c.statContext(context.Background(), fullPath)
+}
+
+// statContext получает метаданные элемента с учетом контекста
+func (c *CloudClient) statContext(ctx context.Context, fullPath string) (*CloudStructureEntry, error) {
+	if fullPath == "" {
+		return nil, &CloudClientError{
+			Message:   "Путь не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+	return c.getEntryContext(ctx, fullPath)
+}
+
+// StatMany получает метаданные нескольких элементов, запрашивая список каждой родительской папки
+// только один раз. Ключи результата совпадают с переданными путями; для несуществующих элементов
+// значение равно nil
+func (c *CloudClient) StatMany(paths []string) (map[string]*CloudStructureEntryBase, error) {
+	byParent := map[string][]string{}
+	for _, path := range paths {
+		parent := c.getParentCloudPath(c.getPathStartEndSlash(path, true, false))
+		byParent[parent] = append(byParent[parent], path)
+	}
+
+	result := make(map[string]*CloudStructureEntryBase, len(paths))
+	for parent, children := range byParent {
+		for _, path := range children {
+			result[path] = nil
+		}
+
+		folder, err := c.GetFolder(parent)
+		if hasErrorCode(err, ErrorCodePathNotExists) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		items := make(map[string]*CloudStructureEntry, len(folder.Items))
+		for _, item := range folder.Items {
+			items[strings.TrimSuffix(item.Home, "/")] = item
+		}
+
+		for _, path := range children {
+			item, ok := items[strings.TrimSuffix(c.getPathStartEndSlash(path, true, false), "/")]
+			if !ok {
+				continue
+			}
+			if item.IsFolder() {
+				result[path] = &newFolderFromEntry(item, c).CloudStructureEntryBase
+			} else {
+				result[path] = &newFileFromEntry(item, c).CloudStructureEntryBase
+			}
+		}
+	}
+	return result, nil
+}
+
+// ExistsMany проверяет существование нескольких элементов. Как и StatMany, список каждой родительской
+// папки запрашивается только один раз, поэтому метод подходит для сравнения больших наборов путей,
+// сгруппированных в немногих папках. Если родительская папка не существует, все ее пути отмечаются false
+func (c *CloudClient) ExistsMany(paths []string) (map[string]bool, error) {
+	entries, err := c.StatMany(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(entries))
+	for path, entry := range entries {
+		result[path] = entry != nil
+	}
+	return result, nil
+}
+
+// IsDir проверяет, что элемент по указанному пути является папкой
+func (c *CloudClient) IsDir(fullPath string) (bool, error) {
+	entry, err := c.Stat(fullPath)
+	if err != nil {
+		return false, err
+	}
+	return entry.IsFolder(), nil
+}
+
+// IsFile проверяет, что элемент по указанному пути является файлом
+func (c *CloudClient) IsFile(fullPath string) (bool, error) {
+	entry, err := c.Stat(fullPath)
+	if err != nil {
+		return false, err
+	}
+	return !entry.IsFolder(), nil
+}
+
+// defaultRecentFilesLimit количество недавних файлов по умолчанию
+const defaultRecentFilesLimit = 50
+
+// GetRecentFiles получает недавно добавленные и измененные файлы всего аккаунта (раздел "Последние"
+// веб-интерфейса), отсортированные по времени изменения от новых к старым, без обхода дерева папок.
+// Используется эндпоинт RecentURL, который возвращает одну страницу из не более limit файлов
+// (при limit <= 0 - defaultRecentFilesLimit); постраничное получение API не поддерживает
+func (c *CloudClient) GetRecentFiles(limit int) ([]*File, error) {
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultRecentFilesLimit
+	}
+
+	recentURL := fmt.Sprintf(c.Account.cloudURL()+RecentURL, c.Account.getAuthToken(), limit)
+	req, err := c.newRequest(context.Background(), "GET", recentURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("получение недавних файлов не удалось: статус %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var deserialized CloudStructureEntry
+	if err := c.Account.decodeResponse(body, &deserialized); err != nil {
+		return nil, err
+	}
+
+	files := []*File{}
+	for _, item := range deserialized.List {
+		if !item.IsFolder() {
+			files = append(files, newFileFromEntry(item, c))
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].LastModifiedTimeUTC.After(files[j].LastModifiedTimeUTC)
+	})
+	if len(files) > limit {
+		files = files[:limit]
+	}
+	return files, nil
+}
+
+// getEntry получает метаданные элемента облака по полному пути без получения списка родительской папки
+func (c *CloudClient) getEntry(fullPath string) (*CloudStructureEntry, error) {
+	return c.getEntryContext(context.Background(), fullPath)
+}
+
+// getEntryContext получает метаданные элемента облака с учетом контекста
+func (c *CloudClient) getEntryContext(ctx context.Context, fullPath string) (*CloudStructureEntry, error) {
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+
+	fullPath = c.getPathStartEndSlash(fullPath, true, false)
+	fileInfoURL := fmt.Sprintf(c.Account.cloudURL()+FileInfoURL, c.Account.getAuthToken(), url.QueryEscape(fullPath))
+	req, err := c.newRequest(ctx, "GET", fileInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &CloudClientError{
+			Message:   "Элемент по указанному пути не существует",
+			Source:    fullPath,
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry CloudStructureEntry
+	if err := c.Account.decodeResponse(body, &entry); err != nil {
+		return nil, err
+	}
+
+	if entry.Home == "" {
+		entry.Home = fullPath
+	}
+	if entry.Name == "" {
+		entry.Name = filepath.Base(fullPath)
+	}
+	return &entry, nil
+}
+
+// GetLinkStats получает статистику публичной ссылки опубликованного элемента: количество просмотров,
+// скачиваний и время последнего обращения. Счетчики, которые сервер не вернул, остаются нулевыми.
+// Если элемент не опубликован, возвращается ошибка с кодом ErrorCodePublicLinkNotExists
+func (c *CloudClient) GetLinkStats(fullPath string) (*LinkStats, error) {
+	entry, err := c.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Weblink == "" {
+		return nil, &CloudClientError{
+			Message:   "Элемент не опубликован",
+			Source:    entry.Home,
+			ErrorCode: ErrorCodePublicLinkNotExists,
+		}
+	}
+
+	return &LinkStats{
+		PublicLink:        c.Account.buildPublicLink(entry.Weblink),
+		Views:             entry.WeblinkViews,
+		Downloads:         entry.WeblinkDownloads,
+		LastAccessTimeUTC: unixTimeUTC(entry.WeblinkAccessTime),
+	}, nil
+}
+
+// ListSharedWithMe получает список папок, которые другие пользователи предоставили в общий доступ текущему аккаунту.
+// Папки, доступные только для чтения, помечены ReadOnly: изменяющие методы возвращенных объектов Folder
+// возвращают ErrorCodeAccessDenied. Методы CloudClient и папки, полученные через GetFolder, доступ не
+// проверяют - такие операции отклоняет сервер
+func (c *CloudClient) ListSharedWithMe() ([]*Folder, error) {
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+
+	sharedURL := fmt.Sprintf(c.Account.cloudURL()+SharedIncomingURL, c.Account.getAuthToken())
+	req, err := c.newRequest(context.Background(), "GET", sharedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("получение общих папок не удалось: статус %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var deserialized CloudStructureEntry
+	if err := c.Account.decodeResponse(body, &deserialized); err != nil {
+		return nil, err
+	}
+
+	folders := []*Folder{}
+	for _, item := range deserialized.List {
+		folder := newFolderFromEntry(item, c)
+		folder.Owner = item.Owner
+		folder.ReadOnly = item.Access == accessReadOnly
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
+
+// checkAuthorization проверяет авторизацию
+func (c *CloudClient) checkAuthorization() error {
+	_, err := c.Account.CheckAuthorization()
+	return err
+}
+
+// SetDefaultHeaders устанавливает дополнительные заголовки, добавляемые ко всем исходящим запросам клиента.
+// Заголовки применяются после собственных заголовков библиотеки и заменяют их (например, User-Agent),
+// за исключением заголовков, критичных для авторизации и передачи данных (Cookie, Content-Type,
+// Content-Length, Host, Range): такие заголовки игнорируются. Заголовки добавляются и к запросам авторизации
+// аккаунта. Метод следует вызывать до начала работы с клиентом
+func (c *CloudClient) SetDefaultHeaders(headers http.Header) {
+	c.Account.defaultHeaders = headers.Clone()
+}
+
+// newRequest создает HTTP запрос к облаку с заголовками библиотеки и пользовательскими заголовками по умолчанию
+func (c *CloudClient) newRequest(ctx context.Context, method, requestURL string, body io.Reader) (*http.Request, error) {
+	return c.Account.newRequest(ctx, method, requestURL, body)
+}
+
+// newFormRequest создает POST запрос с данными формы
+func (c *CloudClient) newFormRequest(ctx context.Context, requestURL string, formData url.Values) (*http.Request, error) {
+	req, err := c.newRequest(ctx, "POST", requestURL, strings.NewReader(formData.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// do выполняет HTTP запрос клиентом аккаунта
+func (c *CloudClient) do(req *http.Request) (*http.Response, error) {
+	if err := c.checkReadOnly(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.isLoginPageResponse(req, resp) {
+		// Закрытие тела освобождает место в семафоре до обновления авторизации, которое само выполняет запросы
+		drainAndClose(resp.Body)
+		return nil, c.Account.handleSessionExpired(req.Context(), req.URL.Path)
+	}
+	return resp, nil
+}
+
+// send выполняет HTTP запрос с учетом ограничения одновременных запросов, статистики и метрик клиента.
+// Используется do и запросами авторизации аккаунта, поэтому не проверяет режим только для чтения и
+// истечение сессии
+func (c *CloudClient) send(req *http.Request) (*http.Response, error) {
+	release, err := c.acquireRequestSlot(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	c.stats.requests.Add(1)
+	start := time.Now()
+	resp, err := c.Account.getHttpClient().Do(req)
+	if err != nil {
+		c.stats.failures.Add(1)
+		c.observeRequest(req, 0, time.Since(start))
+		release()
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		c.stats.failures.Add(1)
+	}
+	c.observeRequest(req, resp.StatusCode, time.Since(start))
+
+	if c.requestSlots != nil {
+		resp.Body = &slotReleasingBody{ReadCloser: resp.Body, release: release}
+	}
+	return resp, nil
+}
+
+// readOnlyPostPaths адреса POST запросов, которые не изменяют облако
+var readOnlyPostPaths = []string{
+	DownloadTokenURL,
+	CreateZipArchive,
+	FileRequest + "history",
+}
+
+// checkReadOnly возвращает ошибку с кодом ErrorCodeReadOnly, если клиент работает в режиме только
+// для чтения, а запрос изменяет облако. Изменяющими считаются все запросы, кроме GET, HEAD
+// и POST запросов из readOnlyPostPaths
+func (c *CloudClient) checkReadOnly(req *http.Request) error {
+	if !c.readOnly || req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return nil
+	}
+	if req.Method == http.MethodPost {
+		for _, path := range readOnlyPostPaths {
+			if strings.HasSuffix(req.URL.Path, path) {
+				return nil
+			}
+		}
+	}
+	return newReadOnlyError(req.URL.Path)
+}
+
+// newReadOnlyError возвращает ошибку изменяющей операции в режиме только для чтения
+func newReadOnlyError(source string) error {
+	return &CloudClientError{
+		Message:   "Клиент работает в режиме только для чтения",
+		Source:    source,
+		ErrorCode: ErrorCodeReadOnly,
+	}
+}
+
+// acquireRequestSlot занимает место в семафоре одновременных запросов, ожидая его освобождения
+// или отмены ctx, и возвращает функцию освобождения места
+func (c *CloudClient) acquireRequestSlot(ctx context.Context) (func(), error) {
+	if c.requestSlots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case c.requestSlots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-c.requestSlots })
+	}, nil
+}
+
+// slotReleasingBody тело ответа, освобождающее место в семафоре запросов при закрытии
+type slotReleasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close закрывает тело ответа и освобождает место в семафоре
+func (b *slotReleasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// isLoginPageResponse проверяет, что на запрос к API облака вместо JSON получена HTML страница.
+// Так сервер отвечает, когда сессия недействительна: перенаправляет на страницу входа со статусом 200
+func (c *CloudClient) isLoginPageResponse(req *http.Request, resp *http.Response) bool {
+	if !strings.HasPrefix(req.URL.String(), c.Account.cloudURL()+"/api/") {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(resp.Header.Get("Content-Type")), "text/html")
+}
+
+// GetShards получает актуальный список шардов диспетчера облака: адреса для загрузки, скачивания,
+// публичных ссылок и т.д. Может использоваться для собственной логики загрузки и скачивания
+func (c *CloudClient) GetShards() (*ShardsList, error) {
+	return c.getShardsInfo()
+}
+
+// maxDrainBytes максимальный объем непрочитанного тела ответа, который вычитывается перед закрытием.
+// Если осталось больше, соединение закрывается, так как дочитывание обойдется дороже нового соединения
+const maxDrainBytes = 64 * 1024
+
+// drainAndClose вычитывает остаток тела ответа и закрывает его, чтобы соединение вернулось в пул keep-alive
+func drainAndClose(body io.ReadCloser) {
+	_, _ = io.CopyN(io.Discard, body, maxDrainBytes)
+	body.Close()
+}
+
+// getShardsInfo получает информацию о шардах
+func (c *CloudClient) getShardsInfo() (*ShardsList, error) {
+	return c.getShardsInfoContext(context.Background())
+}
+
+// getShardsInfoContext получает информацию о шардах с учетом контекста
+func (c *CloudClient) getShardsInfoContext(ctx context.Context) (*ShardsList, error) {
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+
+	dispatcherURL := fmt.Sprintf(c.Account.cloudURL()+Dispatcher, c.Account.getAuthToken())
+	req, err := c.newRequest(ctx, "GET", dispatcherURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -496,13 +1398,47 @@ func (c *CloudClient) getShardsInfo() (*ShardsList, error) {
 	}
 
 	var shardsList ShardsList
-	if err := deserializeJSON(body, &shardsList); err != nil {
+	if err := c.Account.decodeResponse(body, &shardsList); err != nil {
 		return nil, err
 	}
 
 	return &shardsList, nil
 }
 
+// dispatcherAttempts количество запросов к диспетчеру, если он не вернул шард нужного типа
+const dispatcherAttempts = 2
+
+// getShardURL возвращает адрес первого шарда типа kind, выбранного из ответа диспетчера функцией
+// shardsOf. Если диспетчер не вернул шарды этого типа, запрос повторяется один раз, после чего
+// возвращается ошибка с кодом ErrorCodeNoShardAvailable
+func (c *CloudClient) getShardURL(kind string, shardsOf func(shards *ShardsList) []*ShardInfo) (string, error) {
+	for attempt := 1; attempt <= dispatcherAttempts; attempt++ {
+		shards, err := c.getShardsInfo()
+		if err != nil {
+			return "", err
+		}
+		if list := shardsOf(shards); len(list) > 0 && list[0].URL != "" {
+			return list[0].URL, nil
+		}
+		if attempt < dispatcherAttempts {
+			c.logRetry("dispatcher", kind, attempt, newNoShardAvailableError(kind))
+		}
+	}
+
+	err := newNoShardAvailableError(kind)
+	c.logGiveUp("dispatcher", kind, dispatcherAttempts, err)
+	return "", err
+}
+
+// newNoShardAvailableError возвращает ошибку отсутствия шарда типа kind в ответе диспетчера
+func newNoShardAvailableError(kind string) error {
+	return &CloudClientError{
+		Message:   "Диспетчер не вернул шарды " + kind,
+		Source:    kind,
+		ErrorCode: ErrorCodeNoShardAvailable,
+	}
+}
+
 // getDefaultFormDataFields получает поля формы данных по умолчанию
 func (c *CloudClient) getDefaultFormDataFields(sourceFullPath ...string) map[string]interface{} {
 	result := map[string]interface{}{
@@ -552,7 +1488,7 @@ func (c *CloudClient) getParentCloudPath(path string) string {
 }
 
 // createFileOrFolder создает новый файл или папку в облаке
-func (c *CloudClient) createFileOrFolder(addFile bool, path, hash string, size int64, rewriteExisting bool) (*struct {
+func (c *CloudClient) createFileOrFolder(addFile bool, path, hash string, size int64, conflict ConflictMode) (*struct {
 	NewName string
 	NewPath string
 }, error) {
@@ -561,11 +1497,7 @@ func (c *CloudClient) createFileOrFolder(addFile bool, path, hash string, size i
 	}
 
 	values := c.getDefaultFormDataFields(path)
-	if rewriteExisting {
-		values["conflict"] = "rewrite"
-	} else {
-		values["conflict"] = "rename"
-	}
+	values["conflict"] = conflict.apiValue()
 
 	if addFile && hash != "" && size != 0 {
 		values["hash"] = hash
@@ -577,32 +1509,48 @@ func (c *CloudClient) createFileOrFolder(addFile bool, path, hash string, size i
 		operationType = "file"
 	}
 
-	createURL := fmt.Sprintf(BaseMailRuCloud+CreateFileOrFolder, operationType)
+	createURL := fmt.Sprintf(c.Account.cloudURL()+CreateFileOrFolder, operationType)
 	formData := url.Values{}
 	for k, v := range values {
 		formData.Set(k, fmt.Sprintf("%v", v))
 	}
 
-	req, err := http.NewRequest("POST", createURL, strings.NewReader(formData.Encode()))
+	req, err := c.newFormRequest(context.Background(), createURL, formData)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		apiError := parseHomeError(body)
+		if resp.StatusCode == http.StatusInsufficientStorage || apiError == "overquota" {
+			return nil, c.insufficientStorageError(path, size)
+		}
+		if addFile && hash != "" && apiError != "exists" {
+			return nil, &CloudClientError{
+				Message:   fmt.Sprintf("Содержимое с указанным хешем отсутствует в облаке (статус %d, ошибка %q)", resp.StatusCode, apiError),
+				Source:    path,
+				ErrorCode: ErrorCodeHashNotExists,
+			}
+		}
+		return nil, &CloudClientError{
+			Message: fmt.Sprintf("Не удалось создать элемент: статус %d, ошибка %q", resp.StatusCode, apiError),
+			Source:  path,
+		}
+	}
+
 	var newPath string
-	if err := deserializeJSON(body, &newPath); err != nil {
+	if err := c.Account.decodeResponse(body, &newPath); err != nil {
 		return nil, err
 	}
 
@@ -616,11 +1564,119 @@ func (c *CloudClient) createFileOrFolder(addFile bool, path, hash string, size i
 	}, nil
 }
 
-// moveOrCopyInternal перемещает или копирует элемент структуры облака
-func (c *CloudClient) moveOrCopyInternal(sourceFullPath, destFolderPath string, move bool) (*CloudStructureEntryBase, error) {
-	if sourceFullPath == "" {
-		return nil, &CloudClientError{
-			Message:   "Путь не может быть пустым",
+// parseHomeError извлекает код ошибки сервера для поля home из ответа вида {"body":{"home":{"error":"exists"}}}
+func parseHomeError(body []byte) string {
+	var resp struct {
+		Home struct {
+			Error string `json:"error"`
+		} `json:"home"`
+	}
+	if err := deserializeJSON(body, &resp); err != nil {
+		return ""
+	}
+	return resp.Home.Error
+}
+
+// CreateFileFromHash создает файл из содержимого, уже имеющегося в облаке, по его хешу Mail.ru и размеру
+// без передачи данных. Если содержимого с таким хешем в облаке нет, возвращается ошибка
+// с кодом ErrorCodeHashNotExists
+func (c *CloudClient) CreateFileFromHash(fullPath, hash string, size int64, conflict ConflictMode) (*File, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(fullPath)
+	}
+	if fullPath == "" {
+		return nil, &CloudClientError{
+			Message:   "Путь не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	if hash == "" || size <= 0 {
+		return nil, &CloudClientError{
+			Message:   "Хеш и размер содержимого должны быть заданы",
+			Source:    "hash",
+			ErrorCode: ErrorCodeHashNotExists,
+		}
+	}
+
+	fullPath = c.getPathStartEndSlash(fullPath, true, false)
+	if conflict == ConflictModeSkip {
+		existing, err := c.getEntry(fullPath)
+		if err == nil && !existing.IsFolder() {
+			return newFileFromEntry(existing, c), nil
+		}
+		if err != nil && !hasErrorCode(err, ErrorCodePathNotExists) {
+			return nil, err
+		}
+	}
+
+	created, err := c.createFileOrFolder(true, fullPath, hash, size, conflict)
+	if err != nil {
+		return nil, err
+	}
+	return c.createUploadedFile(created, hash, size), nil
+}
+
+// CopyByHash копирует файл по полному пути назначения, создавая его по хешу и размеру источника
+// без передачи данных. Если хеш источника недоступен (например, для пустого файла) или сервер
+// не принимает его, выполняется обычное копирование через Copy с последующим переименованием
+func (c *CloudClient) CopyByHash(sourcePath, destPath string) (*File, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(destPath)
+	}
+	if destPath == "" {
+		return nil, &CloudClientError{
+			Message:   "Путь назначения не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	source, err := c.Stat(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	if source.IsFolder() {
+		return nil, &CloudClientError{
+			Message:   "Копирование по хешу поддерживается только для файлов",
+			Source:    "sourcePath",
+			ErrorCode: ErrorCodeNotSupportedOperation,
+		}
+	}
+
+	if source.Hash != "" && source.Size > 0 {
+		file, err := c.CreateFileFromHash(destPath, source.Hash, source.Size, ConflictModeRename)
+		if err == nil || !hasErrorCode(err, ErrorCodeHashNotExists) {
+			return file, err
+		}
+	}
+
+	destPath = c.getPathStartEndSlash(destPath, true, false)
+	copied, err := c.Copy(sourcePath, c.getParentCloudPath(destPath))
+	if err != nil {
+		return nil, err
+	}
+
+	if destName := filepath.Base(destPath); copied.Name != destName {
+		if copied, err = c.Rename(copied.FullPath, destName); err != nil {
+			return nil, err
+		}
+	}
+
+	return &File{
+		CloudStructureEntryBase: *copied,
+		Hash:                    source.Hash,
+		LastModifiedTimeUTC:     unixTimeUTC(source.Mtime),
+	}, nil
+}
+
+// moveOrCopyInternal перемещает или копирует элемент структуры облака
+func (c *CloudClient) moveOrCopyInternal(sourceFullPath, destFolderPath string, move bool, options MoveOptions) (*CloudStructureEntryBase, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(sourceFullPath)
+	}
+	if sourceFullPath == "" {
+		return nil, &CloudClientError{
+			Message:   "Путь не может быть пустым",
 			ErrorCode: ErrorCodePathNotExists,
 		}
 	}
@@ -644,13 +1700,21 @@ func (c *CloudClient) moveOrCopyInternal(sourceFullPath, destFolderPath string,
 		return nil, err
 	}
 
-	_, err = c.GetFolder(destFolderPath)
-	if err != nil {
-		return nil, &CloudClientError{
-			Message:   "Папка назначения не существует в облаке",
-			Source:    "destFolderPath",
-			ErrorCode: ErrorCodePathNotExists,
+	// Отсутствующей считается только папка с ошибкой ErrorCodePathNotExists; прочие ошибки
+	// (сеть, авторизация) возвращаются без изменений
+	if _, err = c.GetFolder(destFolderPath); hasErrorCode(err, ErrorCodePathNotExists) {
+		if !options.CreateDest {
+			return nil, &CloudClientError{
+				Message:   "Папка назначения не существует в облаке",
+				Source:    "destFolderPath",
+				ErrorCode: ErrorCodePathNotExists,
+			}
+		}
+		if _, err := c.EnsureFolder(destFolderPath); err != nil {
+			return nil, fmt.Errorf("создание папки назначения %s не удалось: %w", destFolderPath, err)
 		}
+	} else if err != nil {
+		return nil, err
 	}
 
 	values := c.getDefaultFormDataFields(sourceFullPath)
@@ -666,18 +1730,16 @@ func (c *CloudClient) moveOrCopyInternal(sourceFullPath, destFolderPath string,
 		operation = "move"
 	}
 
-	req, err := http.NewRequest("POST", BaseMailRuCloud+FileRequest+operation, strings.NewReader(formData.Encode()))
+	req, err := c.newFormRequest(context.Background(), c.Account.cloudURL()+FileRequest+operation, formData)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -685,7 +1747,7 @@ func (c *CloudClient) moveOrCopyInternal(sourceFullPath, destFolderPath string,
 	}
 
 	var newPath string
-	if err := deserializeJSON(body, &newPath); err != nil {
+	if err := c.Account.decodeResponse(body, &newPath); err != nil {
 		return nil, err
 	}
 
@@ -740,14 +1802,17 @@ func (c *CloudClient) preparePublishLink(link string) (string, *CloudStructureEn
 }
 
 // prepareUnpublishLink подготавливает ссылку для отмены публикации
-func prepareUnpublishLink(link string) string {
-	return strings.Replace(link, PublicLink, "", 1)
+func (c *CloudClient) prepareUnpublishLink(link string) string {
+	return strings.Replace(link, c.Account.publicLinkPrefix(), "", 1)
 }
 
 // preparePublishRequestData подготавливает данные для запроса публикации
-func (c *CloudClient) preparePublishRequestData(link string) url.Values {
+func (c *CloudClient) preparePublishRequestData(link string, options *PublishOptions) url.Values {
 	values := c.getDefaultFormDataFields(link)
 	delete(values, "conflict")
+	if options != nil && options.MaxDownloads > 0 {
+		values["weblink_downloads_limit"] = options.MaxDownloads
+	}
 	return c.formDataToValues(values)
 }
 
@@ -771,18 +1836,16 @@ func (c *CloudClient) formDataToValues(values map[string]interface{}) url.Values
 
 // executePublishUnpublishRequest выполняет запрос публикации/отмены публикации
 func (c *CloudClient) executePublishUnpublishRequest(operation string, formData url.Values, publish bool) (string, error) {
-	req, err := http.NewRequest("POST", BaseMailRuCloud+FileRequest+operation, strings.NewReader(formData.Encode()))
+	req, err := c.newFormRequest(context.Background(), c.Account.cloudURL()+FileRequest+operation, formData)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
 		errorCode := ErrorCodePathNotExists
@@ -802,7 +1865,7 @@ func (c *CloudClient) executePublishUnpublishRequest(operation string, formData
 	}
 
 	var result string
-	if err := deserializeJSON(body, &result); err != nil {
+	if err := c.Account.decodeResponse(body, &result); err != nil {
 		return "", err
 	}
 
@@ -810,7 +1873,10 @@ func (c *CloudClient) executePublishUnpublishRequest(operation string, formData
 }
 
 // publishUnpublishInternal публикует или отменяет публикацию файла или папки
-func (c *CloudClient) publishUnpublishInternal(link string, publish bool) (*CloudStructureEntryBase, error) {
+func (c *CloudClient) publishUnpublishInternal(link string, publish bool, options *PublishOptions) (*CloudStructureEntryBase, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(link)
+	}
 	if link == "" {
 		return nil, &CloudClientError{
 			Message:   "Ссылка не может быть пустой",
@@ -831,9 +1897,9 @@ func (c *CloudClient) publishUnpublishInternal(link string, publish bool) (*Clou
 		if err != nil {
 			return nil, err
 		}
-		formData = c.preparePublishRequestData(link)
+		formData = c.preparePublishRequestData(link, options)
 	} else {
-		link = prepareUnpublishLink(link)
+		link = c.prepareUnpublishLink(link)
 		formData = c.prepareUnpublishRequestData(link)
 	}
 
@@ -842,315 +1908,943 @@ func (c *CloudClient) publishUnpublishInternal(link string, publish bool) (*Clou
 		operation = "publish"
 	}
 
-	result, err := c.executePublishUnpublishRequest(operation, formData, publish)
+	result, err := c.executePublishUnpublishRequest(operation, formData, publish)
+	if err != nil {
+		return nil, err
+	}
+
+	if !publish {
+		return c.checkUnknownItemExisting(result)
+	}
+
+	item.PublicLink = c.Account.buildPublicLink(result)
+	return item, nil
+}
+
+// AbortAllAsyncTasks прерывает выполняющиеся асинхронные задачи
+func (c *CloudClient) AbortAllAsyncTasks() {
+	if c.cancelToken != nil {
+		c.cancelToken()
+	}
+}
+
+// UploadFile загружает файл в облако. Лимит загрузки 4GB
+func (c *CloudClient) UploadFile(destFileName, sourceFilePath, destFolderPath string) (*File, error) {
+	return c.UploadFileWithResolver(destFileName, sourceFilePath, destFolderPath, nil)
+}
+
+// UploadFileWithResolver загружает файл в облако, определяя поведение при конфликте имен через resolve.
+// Функция resolve вызывается только если в папке назначения уже существует файл с таким именем
+// и получает его метаданные. Если resolve равна nil, используется ConflictModeRename.
+// При ConflictModeSkip загрузка не выполняется и возвращается существующий файл
+func (c *CloudClient) UploadFileWithResolver(destFileName, sourceFilePath, destFolderPath string, resolve func(existing *File) ConflictMode) (*File, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(destFolderPath + "/" + destFileName)
+	}
+	if sourceFilePath == "" {
+		return nil, &CloudClientError{
+			Message:   "Путь к исходному файлу не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	// Открытие файла
+	file, err := os.Open(sourceFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result, err := c.uploadFromStream(uploadFileName(destFileName, sourceFilePath), file, destFolderPath, resolve)
+	if err != nil {
+		return nil, err
+	}
+	return result.File, nil
+}
+
+// UploadIfNewer загружает локальный файл sourceFilePath в папку destFolderPath под именем destFileName
+// (пустое имя - имя исходного файла), только если удаленного файла нет или он изменен раньше
+// локального. Существующий файл перезаписывается. Возвращает файл в облаке (удаленный, если загрузка
+// не выполнялась) и признак выполненной загрузки. Расхождение часов учитывается через WithClockSkewTolerance
+func (c *CloudClient) UploadIfNewer(destFileName, sourceFilePath, destFolderPath string, opts ...UploadOption) (*File, bool, error) {
+	if c.readOnly {
+		return nil, false, newReadOnlyError(destFolderPath + "/" + destFileName)
+	}
+	if sourceFilePath == "" {
+		return nil, false, &CloudClientError{
+			Message:   "Путь к исходному файлу не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	info, err := os.Stat(sourceFilePath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	destFileName = uploadFileName(destFileName, sourceFilePath)
+	remotePath := c.getPathStartEndSlash(destFolderPath, true, true) + destFileName
+	entry, err := c.Stat(remotePath)
+	if err != nil && !hasErrorCode(err, ErrorCodePathNotExists) {
+		return nil, false, err
+	}
+
+	if entry != nil {
+		remote := newFileFromEntry(entry, c)
+		tolerance := newUploadOptions(opts).clockSkewTolerance
+		if !info.ModTime().After(remote.LastModifiedTimeUTC.Add(tolerance)) {
+			return remote, false, nil
+		}
+	}
+
+	file, err := os.Open(sourceFilePath)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	result, err := c.uploadFromStream(destFileName, file, destFolderPath, func(existing *File) ConflictMode {
+		return ConflictModeRewrite
+	}, opts...)
+	if err != nil {
+		return nil, false, err
+	}
+	return result.File, true, nil
+}
+
+// uploadFileName определяет имя файла в облаке по имени назначения и пути к исходному файлу
+func uploadFileName(destFileName, sourceFilePath string) string {
+	originalFileName := filepath.Base(sourceFilePath)
+	extension := filepath.Ext(originalFileName)
+	if destFileName == "" {
+		destFileName = originalFileName
+	} else if extension != "" && !strings.HasSuffix(strings.ToLower(destFileName), strings.ToLower(extension)) {
+		destFileName += extension
+	}
+	return destFileName
+}
+
+// validateUploadParams проверяет параметры загрузки и возвращает папку назначения
+func (c *CloudClient) validateUploadParams(destFileName, destFolderPath string) (*Folder, error) {
+	if destFileName == "" {
+		return nil, &CloudClientError{
+			Message:   "Имя файла не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+	if err := IsValidCloudName(destFileName); err != nil {
+		return nil, err
+	}
+
+	if destFolderPath == "" {
+		return nil, &CloudClientError{
+			Message:   "Путь к папке назначения не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	folder, err := c.GetFolder(destFolderPath)
+	if err != nil || folder == nil {
+		return nil, &CloudClientError{
+			Message:   "Путь не существует",
+			Source:    "destFolderPath",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+	return folder, nil
+}
+
+// readUploadContent читает содержимое для загрузки
+func readUploadContent(content io.Reader) ([]byte, error) {
+	contentBytes, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contentBytes) == 0 {
+		return nil, emptyUploadContentError()
+	}
+	return contentBytes, nil
+}
+
+// prepareUploadContent подготавливает содержимое для загрузки и определяет его размер.
+// Потоки с поддержкой io.Seeker передаются без буферизации начиная с текущей позиции,
+// потоки с известным размером contentLength передаются без буферизации как есть,
+// остальные потоки читаются в память целиком
+func prepareUploadContent(content io.Reader, contentLength int64) (io.Reader, int64, error) {
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, err
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+
+		if end-start <= 0 {
+			return nil, 0, emptyUploadContentError()
+		}
+		return seeker, end - start, nil
+	}
+
+	if contentLength > 0 {
+		return content, contentLength, nil
+	}
+
+	contentBytes, err := readUploadContent(content)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(contentBytes), int64(len(contentBytes)), nil
+}
+
+// emptyUploadContentError возвращает ошибку пустого содержимого загрузки
+func emptyUploadContentError() error {
+	return &CloudClientError{
+		Message:   "Содержимое не может быть пустым",
+		ErrorCode: ErrorCodePathNotExists,
+	}
+}
+
+// validateUploadFileSize проверяет размер файла для загрузки
+func (c *CloudClient) validateUploadFileSize(fileSize int64) error {
+	sizeLimit := uploadSizeLimit2GB
+	if !c.Account.Has2GBUploadSizeLimit() {
+		sizeLimit = uploadSizeLimit32GB
+	}
+
+	if fileSize > sizeLimit {
+		return &CloudClientError{
+			Message:   fmt.Sprintf("Максимальный лимит размера загрузки составляет %dGB", sizeLimit/(1024*1024*1024)),
+			Source:    "content",
+			ErrorCode: ErrorCodeUploadingSizeLimit,
+		}
+	}
+	return nil
+}
+
+// getUploadShardURL получает URL шарда для загрузки
+func (c *CloudClient) getUploadShardURL() (string, error) {
+	shardURL, err := c.getShardURL("Upload", func(shards *ShardsList) []*ShardInfo { return shards.Upload })
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(UploadFile, shardURL, c.Account.Email), nil
+}
+
+// uploadToShard загружает файл на шард. Повторные попытки выполняются только для потоков
+// с поддержкой io.Seeker: перед каждой из них поток перематывается на позицию, с которой
+// началась первая попытка, поэтому повтор не отправляет усеченные данные.
+// При включенной проверке хеша (WithChunkVerification) хеш, возвращенный шардом, сравнивается
+// с вычисленным при отправке, и при расхождении содержимое отправляется повторно
+func (c *CloudClient) uploadToShard(uploadURL, destPath string, content io.Reader, fileSize int64) (string, error) {
+	seeker, seekable := content.(io.Seeker)
+	var start int64
+	if seekable {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			return "", err
+		}
+	}
+
+	progress := newProgressTracker(c, destPath, fileSize)
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return "", err
+			}
+			progress.restart()
+		}
+
+		var reader io.Reader = io.LimitReader(content, fileSize)
+		var hasher *mailRuHasher
+		if c.chunkVerification {
+			hasher = newMailRuHasher()
+			reader = io.TeeReader(reader, hasher)
+		}
+
+		resp, err := c.putToShard(uploadURL, &progressReader{reader: c.throttleUpload(reader), tracker: progress}, fileSize)
+		if seekable && c.shouldRetryUpload(attempt, resp, err) {
+			c.logRetry("upload", destPath, attempt+1, uploadAttemptError(resp, err))
+			if resp != nil {
+				drainAndClose(resp.Body)
+			}
+			continue
+		}
+		if attempt > 0 && isRetryableUploadFailure(resp, err) {
+			c.logGiveUp("upload", destPath, attempt+1, uploadAttemptError(resp, err))
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if resp.StatusCode == http.StatusInsufficientStorage {
+			drainAndClose(resp.Body)
+			return "", c.insufficientStorageError(uploadURL, fileSize)
+		}
+
+		hash, err := readShardHash(uploadURL, resp)
+		if err != nil {
+			return "", err
+		}
+
+		if hasher != nil {
+			if localHash := hasher.Sum(); !strings.EqualFold(hash, localHash) {
+				mismatchErr := &CloudClientError{
+					Message:   fmt.Sprintf("Хеш загруженного содержимого %s не совпадает с отправленным %s", hash, localHash),
+					Source:    uploadURL,
+					ErrorCode: ErrorCodeUploadFailed,
+				}
+				if seekable && attempt < max(c.uploadRetries, 1) && c.cancelCtx.Err() == nil {
+					c.logRetry("upload", destPath, attempt+1, mismatchErr)
+					continue
+				}
+				if attempt > 0 {
+					c.logGiveUp("upload", destPath, attempt+1, mismatchErr)
+				}
+				return "", mismatchErr
+			}
+		}
+
+		progress.finish()
+		return hash, nil
+	}
+}
+
+// insufficientStorageError формирует ошибку нехватки места в облаке с указанием,
+// на сколько байт размещение size байт превышает квоту (если использование диска удалось получить)
+func (c *CloudClient) insufficientStorageError(source string, size int64) error {
+	message := "Недостаточно места в облаке"
+	if usage, err := c.Account.getDiskUsageInternal(context.Background(), false); err == nil {
+		if over := usage.Used.DefaultValue + size - usage.Total.DefaultValue; over > 0 {
+			message = fmt.Sprintf("Недостаточно места в облаке: превышение квоты на %d байт", over)
+		}
+	}
+
+	return &CloudClientError{
+		Message:   message,
+		Source:    source,
+		ErrorCode: ErrorCodeInsufficientStorage,
+	}
+}
+
+// readShardHash проверяет статус ответа шарда загрузки и извлекает из него хеш содержимого
+func readShardHash(uploadURL string, resp *http.Response) (string, error) {
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", &CloudClientError{
+			Message:   fmt.Sprintf("Шард отклонил загрузку файла: статус %d", resp.StatusCode),
+			Source:    uploadURL,
+			ErrorCode: ErrorCodeUploadFailed,
+		}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var hash string
+	if err := deserializeJSON(body, &hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// putToShard выполняет PUT запрос загрузки содержимого на шард
+func (c *CloudClient) putToShard(uploadURL string, body io.Reader, fileSize int64) (*http.Response, error) {
+	req, err := c.newRequest(c.cancelCtx, "PUT", uploadURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = fileSize
+
+	return c.do(req)
+}
+
+// shouldRetryUpload определяет, нужно ли повторить загрузку после сетевой ошибки или ошибки сервера (5xx)
+func (c *CloudClient) shouldRetryUpload(attempt int, resp *http.Response, err error) bool {
+	if attempt >= c.uploadRetries || c.cancelCtx.Err() != nil {
+		return false
+	}
+	return isRetryableUploadFailure(resp, err)
+}
+
+// isRetryableUploadFailure проверяет, что попытка загрузки завершилась сетевой ошибкой или ошибкой сервера (5xx)
+func isRetryableUploadFailure(resp *http.Response, err error) bool {
+	if hasErrorCode(err, ErrorCodeReadOnly) {
+		return false
+	}
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// uploadAttemptError возвращает ошибку неудачной попытки загрузки для журнала
+func uploadAttemptError(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("шард ответил статусом %d", resp.StatusCode)
+}
+
+// logRetry записывает в журнал аккаунта повторную попытку операции operation над path после ошибки
+// и учитывает ее в статистике клиента
+func (c *CloudClient) logRetry(operation, path string, attempt int, err error) {
+	c.stats.retries.Add(1)
+	c.Account.logWarn("повторная попытка после ошибки",
+		"operation", operation, "path", path, "attempt", attempt, "error", err)
+}
+
+// logGiveUp записывает в журнал аккаунта отказ от операции operation над path после attempts попыток
+func (c *CloudClient) logGiveUp(operation, path string, attempts int, err error) {
+	c.Account.logWarn("операция не удалась после всех попыток",
+		"operation", operation, "path", path, "attempts", attempts, "error", err)
+}
+
+// createUploadedFile создает объект File для загруженного файла
+func (c *CloudClient) createUploadedFile(createdFile *struct {
+	NewName string
+	NewPath string
+}, hash string, fileSize int64) *File {
+	return &File{
+		CloudStructureEntryBase: CloudStructureEntryBase{
+			FullPath: createdFile.NewPath,
+			Name:     createdFile.NewName,
+			Size:     NewSize(fileSize),
+			account:  c.Account,
+			client:   c,
+		},
+		Hash:                hash,
+		LastModifiedTimeUTC: time.Now().UTC(),
+	}
+}
+
+// UploadFileFromStream загружает файл в облако из потока.
+// Поток может быть, например, телом http.Response: при передаче WithContentLength(resp.ContentLength)
+// содержимое передается в облако напрямую, без буферизации в памяти и записи на диск
+func (c *CloudClient) UploadFileFromStream(destFileName string, content io.Reader, destFolderPath string, opts ...UploadOption) (*File, error) {
+	result, err := c.uploadFromStream(destFileName, content, destFolderPath, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return result.File, nil
+}
+
+// UploadFileFromStreamWithResult загружает файл в облако из потока и возвращает подробный результат,
+// включая признак расхождения хеша или размера, сообщенных сервером, с переданными значениями
+func (c *CloudClient) UploadFileFromStreamWithResult(destFileName string, content io.Reader, destFolderPath string, opts ...UploadOption) (*UploadResult, error) {
+	return c.uploadFromStream(destFileName, content, destFolderPath, nil, opts...)
+}
+
+// uploadFromStream загружает файл в облако из потока, разрешая конфликт имен через resolve
+func (c *CloudClient) uploadFromStream(destFileName string, content io.Reader, destFolderPath string, resolve func(existing *File) ConflictMode, opts ...UploadOption) (*UploadResult, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(destFolderPath + "/" + destFileName)
+	}
+	options := newUploadOptions(opts)
+
+	if err := c.beginTransfer(); err != nil {
+		return nil, err
+	}
+	defer c.transfers.Done()
+
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+
+	destFolderPath = c.getPathStartEndSlash(destFolderPath, true, true)
+
+	destFolder, err := c.validateUploadParams(destFileName, destFolderPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.uploadToFolder(destFileName, content, destFolder, resolve, options)
+}
+
+// UploadFileTo загружает локальный файл sourceFilePath в папку folder под именем исходного файла.
+// В отличие от UploadFile, путь папки берется из уже полученного объекта, поэтому существование
+// папки повторно не проверяется
+func (c *CloudClient) UploadFileTo(folder *Folder, sourceFilePath string, opts ...UploadOption) (*File, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(sourceFilePath)
+	}
+	if sourceFilePath == "" {
+		return nil, &CloudClientError{
+			Message:   "Путь к исходному файлу не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	file, err := os.Open(sourceFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return c.UploadFileFromStreamTo(folder, uploadFileName("", sourceFilePath), file, opts...)
+}
+
+// UploadFileFromStreamTo загружает содержимое потока в папку folder под именем destFileName
+// без повторной проверки существования папки
+func (c *CloudClient) UploadFileFromStreamTo(folder *Folder, destFileName string, content io.Reader, opts ...UploadOption) (*File, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(destFileName)
+	}
+	if folder == nil {
+		return nil, &CloudClientError{
+			Message:   "Папка назначения не может быть nil",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+	if err := folder.checkWritable(); err != nil {
+		return nil, err
+	}
+	if destFileName == "" {
+		return nil, &CloudClientError{
+			Message:   "Имя файла не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+	if err := IsValidCloudName(destFileName); err != nil {
+		return nil, err
+	}
+
+	if err := c.beginTransfer(); err != nil {
+		return nil, err
+	}
+	defer c.transfers.Done()
+
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+
+	result, err := c.uploadToFolder(destFileName, content, folder, nil, newUploadOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return result.File, nil
+}
+
+// uploadToFolder загружает содержимое в уже проверенную папку назначения destFolder
+func (c *CloudClient) uploadToFolder(destFileName string, content io.Reader, destFolder *Folder, resolve func(existing *File) ConflictMode, options *uploadOptions) (*UploadResult, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(destFolder.FullPath + "/" + destFileName)
+	}
+	destFolderPath := c.getPathStartEndSlash(destFolder.FullPath, true, true)
+
+	conflict := ConflictModeRename
+	if resolve != nil {
+		if existing := findFileByName(destFolder.GetFiles(), destFileName); existing != nil {
+			conflict = resolve(existing)
+			if conflict == ConflictModeSkip {
+				return &UploadResult{File: existing, SentHash: existing.Hash, SentSize: existing.Size.DefaultValue}, nil
+			}
+		}
+	}
+
+	uploadContent, fileSize, err := prepareUploadContent(content, options.contentLength)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.validateUploadFileSize(fileSize); err != nil {
+		return nil, err
+	}
+
+	if seeker, ok := uploadContent.(io.ReadSeeker); ok && options.instant {
+		hash, err := c.computeSeekableHash(seeker, fileSize)
+		if err != nil {
+			return nil, err
+		}
+
+		createdFile, err := c.createFileOrFolder(true, destFolderPath+destFileName, hash, fileSize, conflict)
+		if err == nil {
+			result := c.buildUploadResult(createdFile, hash, fileSize)
+			result.Instant = true
+			return result, nil
+		}
+		if !hasErrorCode(err, ErrorCodeHashNotExists) {
+			return nil, err
+		}
+	}
+
+	uploadURL, err := c.getUploadShardURL()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := c.uploadToShard(uploadURL, destFolderPath+destFileName, uploadContent, fileSize)
 	if err != nil {
 		return nil, err
 	}
 
-	if !publish {
-		return c.checkUnknownItemExisting(result)
+	createdFile, err := c.createFileOrFolder(true, destFolderPath+destFileName, hash, fileSize, conflict)
+	if err != nil {
+		return nil, err
 	}
 
-	item.PublicLink = PublicLink + result
-	return item, nil
-}
-
-// AbortAllAsyncTasks прерывает выполняющиеся асинхронные задачи
-func (c *CloudClient) AbortAllAsyncTasks() {
-	if c.cancelToken != nil {
-		c.cancelToken()
-	}
+	result := c.buildUploadResult(createdFile, hash, fileSize)
+	result.BytesTransferred = fileSize
+	return result, nil
 }
 
-// UploadFile загружает файл в облако. Лимит загрузки 4GB
-func (c *CloudClient) UploadFile(destFileName, sourceFilePath, destFolderPath string) (*File, error) {
-	if sourceFilePath == "" {
-		return nil, &CloudClientError{
-			Message:   "Путь к исходному файлу не может быть пустым",
-			ErrorCode: ErrorCodePathNotExists,
-		}
+// buildUploadResult формирует результат загрузки по метаданным, полученным от сервера.
+// Если метаданные получить не удалось, используются переданные значения
+func (c *CloudClient) buildUploadResult(createdFile *struct {
+	NewName string
+	NewPath string
+}, hash string, fileSize int64) *UploadResult {
+	result := &UploadResult{
+		SentHash: hash,
+		SentSize: fileSize,
 	}
 
-	// Открытие файла
-	file, err := os.Open(sourceFilePath)
+	entry, err := c.getEntry(createdFile.NewPath)
 	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	originalFileName := filepath.Base(sourceFilePath)
-	extension := filepath.Ext(originalFileName)
-	if destFileName == "" {
-		destFileName = originalFileName
-	} else if extension != "" && !strings.HasSuffix(strings.ToLower(destFileName), strings.ToLower(extension)) {
-		destFileName += extension
+		result.File = c.createUploadedFile(createdFile, hash, fileSize)
+		return result
 	}
 
-	return c.UploadFileFromStream(destFileName, file, destFolderPath)
+	result.File = newFileFromEntry(entry, c)
+	result.HashMismatch = entry.Hash != hash || entry.Size != fileSize
+	return result
 }
 
-// validateUploadParams проверяет параметры загрузки
-func (c *CloudClient) validateUploadParams(destFileName, destFolderPath string) error {
-	if destFileName == "" {
-		return &CloudClientError{
-			Message:   "Имя файла не может быть пустым",
-			ErrorCode: ErrorCodePathNotExists,
+// findFileByName ищет файл с указанным именем в списке
+func findFileByName(files []*File, name string) *File {
+	for _, file := range files {
+		if file.Name == name {
+			return file
 		}
 	}
+	return nil
+}
 
-	if destFolderPath == "" {
-		return &CloudClientError{
-			Message:   "Путь к папке назначения не может быть пустым",
+// DownloadFile скачивает файл из облака. Возвращается поток содержимого файла и его размер
+// или -1, если размер неизвестен (например, шард передал содержимое в сжатом виде с Content-Encoding: gzip;
+// такое содержимое распаковывается автоматически)
+func (c *CloudClient) DownloadFile(sourceFilePath string) (io.ReadCloser, int64, error) {
+	if sourceFilePath == "" {
+		return nil, 0, &CloudClientError{
+			Message:   "Путь к файлу не может быть пустым",
 			ErrorCode: ErrorCodePathNotExists,
 		}
 	}
 
-	_, err := c.GetFolder(destFolderPath)
-	if err != nil {
-		return &CloudClientError{
-			Message:   "Путь не существует",
-			Source:    "destFolderPath",
-			ErrorCode: ErrorCodePathNotExists,
-		}
+	sourceFilePath = strings.TrimPrefix(sourceFilePath, "/")
+	if err := c.checkAuthorization(); err != nil {
+		return nil, 0, err
 	}
-	return nil
-}
 
-// readUploadContent читает содержимое для загрузки
-func readUploadContent(content io.Reader) ([]byte, error) {
-	contentBytes, err := io.ReadAll(content)
-	if err != nil {
-		return nil, err
-	}
+	return c.downloadFromGetShard(c.cancelCtx, sourceFilePath, nil, time.Time{})
+}
 
-	if len(contentBytes) == 0 {
-		return nil, &CloudClientError{
-			Message:   "Содержимое не может быть пустым",
+// DownloadFileIfModifiedSince скачивает файл, только если он изменился после ifModifiedSince:
+// время передается шарду в заголовке If-Modified-Since. Если шард отвечает 304 Not Modified,
+// поток не открывается и возвращается CloudClientError с кодом ErrorCodeNotModified.
+// Нулевое время отключает проверку. Позволяет кеширующим прокси не скачивать неизменные файлы
+func (c *CloudClient) DownloadFileIfModifiedSince(sourceFilePath string, ifModifiedSince time.Time) (io.ReadCloser, int64, error) {
+	if sourceFilePath == "" {
+		return nil, 0, &CloudClientError{
+			Message:   "Путь к файлу не может быть пустым",
 			ErrorCode: ErrorCodePathNotExists,
 		}
 	}
-	return contentBytes, nil
-}
 
-// validateUploadFileSize проверяет размер файла для загрузки
-func (c *CloudClient) validateUploadFileSize(fileSize int64) error {
-	sizeLimit := int64(2048 * 1024 * 1024) // 2GB
-	if !c.Account.Has2GBUploadSizeLimit() {
-		sizeLimit = int64(32768 * 1024 * 1024) // 32GB
+	sourceFilePath = strings.TrimPrefix(sourceFilePath, "/")
+	if err := c.checkAuthorization(); err != nil {
+		return nil, 0, err
 	}
 
-	if fileSize > sizeLimit {
-		return &CloudClientError{
-			Message:   fmt.Sprintf("Максимальный лимит размера загрузки составляет %dGB", sizeLimit/(1024*1024*1024)),
-			Source:    "content",
-			ErrorCode: ErrorCodeUploadingSizeLimit,
+	return c.downloadFromGetShard(c.cancelCtx, sourceFilePath, nil, ifModifiedSince)
+}
+
+// DownloadHistoryRevision скачивает содержимое указанной ревизии файла из истории без его восстановления
+func (c *CloudClient) DownloadHistoryRevision(sourceFullPath string, historyRevision int64) (io.ReadCloser, int64, error) {
+	if historyRevision <= 0 {
+		return nil, 0, &CloudClientError{
+			Message:   "Ревизия должна быть больше 0",
+			ErrorCode: ErrorCodeHistoryNotExists,
 		}
 	}
-	return nil
-}
 
-// getUploadShardURL получает URL шарда для загрузки
-func (c *CloudClient) getUploadShardURL() (string, error) {
-	shards, err := c.getShardsInfo()
+	histories, err := c.GetFileHistory(sourceFullPath)
 	if err != nil {
-		return "", err
+		return nil, 0, err
 	}
 
-	if len(shards.Upload) == 0 {
-		return "", fmt.Errorf("шарды Upload не найдены")
+	history, err := findHistoryRevision(histories, historyRevision)
+	if err != nil {
+		return nil, 0, err
 	}
-
-	shardURL := shards.Upload[0].URL
-	return fmt.Sprintf(UploadFile, shardURL, c.Account.Email), nil
-}
-
-// notifyUploadProgress уведомляет о прогрессе загрузки
-func (c *CloudClient) notifyUploadProgress(fileSize int64, percentage int) {
-	if c.ProgressChangedEvent == nil {
-		return
+	// Без хеша шард вернул бы текущее содержимое файла вместо запрошенной ревизии
+	if err := checkHistoryHash(history); err != nil {
+		return nil, 0, err
 	}
 
-	c.ProgressChangedEvent(c, &ProgressChangedEventArgs{
-		ProgressPercentage: percentage,
-		State: &ProgressChangeTaskState{
-			TotalBytes:      NewSize(fileSize),
-			BytesInProgress: NewSize(int64(percentage) * fileSize / 100),
-		},
-	})
+	query := url.Values{}
+	query.Set("hash", history.Hash)
+	sourceFullPath = c.getPathStartEndSlash(sourceFullPath, true, false)
+	return c.downloadFromGetShard(c.cancelCtx, strings.TrimPrefix(sourceFullPath, "/"), query, time.Time{})
 }
 
-// uploadToShard загружает файл на шард
-func (c *CloudClient) uploadToShard(uploadURL string, contentBytes []byte, fileSize int64) (string, error) {
-	req, err := http.NewRequestWithContext(c.cancelCtx, "PUT", uploadURL, bytes.NewReader(contentBytes))
-	if err != nil {
-		return "", err
+// downloadFromGetShard открывает поток скачивания файла с шарда Get по относительному пути.
+// Запросы, включая возобновление, выполняются в контексте ctx.
+// Ненулевое ifModifiedSince передается в заголовке If-Modified-Since
+func (c *CloudClient) downloadFromGetShard(ctx context.Context, filePath string, query url.Values, ifModifiedSince time.Time) (io.ReadCloser, int64, error) {
+	if err := c.beginTransfer(); err != nil {
+		return nil, 0, err
 	}
-	req.Header.Set("User-Agent", UserAgent)
-	req.ContentLength = fileSize
-
-	c.notifyUploadProgress(fileSize, 0)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.openGetShardFile(ctx, filePath, query, 0, ifModifiedSince)
 	if err != nil {
-		return "", err
+		c.transfers.Done()
+		return nil, 0, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	if !resp.Uncompressed && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		// Сжатие запрошено явно (например, заголовком Accept-Encoding из SetDefaultHeaders), поэтому
+		// транспорт не распаковал ответ. Смещения в сжатом потоке не совпадают со смещениями файла,
+		// поэтому возобновление для такого ответа не используется
+		body, err := newGzipReadCloser(resp.Body)
+		if err != nil {
+			drainAndClose(resp.Body)
+			c.transfers.Done()
+			return nil, 0, err
+		}
+		return c.trackTransfer(c.throttleDownload(body)), -1, nil
 	}
 
-	var hash string
-	if err := deserializeJSON(body, &hash); err != nil {
-		return "", err
+	// Для ответа, распакованного транспортом, ContentLength равен -1
+	contentLength := resp.ContentLength
+
+	if !c.downloadResume {
+		return c.trackTransfer(c.throttleDownload(resp.Body)), contentLength, nil
 	}
 
-	c.notifyUploadProgress(fileSize, 100)
-	return hash, nil
+	return c.trackTransfer(c.throttleDownload(&resumingReader{
+		body: resp.Body,
+		onRetry: func(attempt int, err error) {
+			c.logRetry("download", "/"+filePath, attempt, err)
+		},
+		onGiveUp: func(attempts int, err error) {
+			c.logGiveUp("download", "/"+filePath, attempts, err)
+		},
+		reopen: func(offset int64) (io.ReadCloser, error) {
+			if err := c.Account.RefreshTokenContext(ctx); err != nil {
+				return nil, err
+			}
+			resp, err := c.openGetShardFile(ctx, filePath, query, offset, time.Time{})
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode != http.StatusPartialContent {
+				drainAndClose(resp.Body)
+				return nil, fmt.Errorf("шард не поддерживает возобновление скачивания: статус %d", resp.StatusCode)
+			}
+			return resp.Body, nil
+		},
+	})), contentLength, nil
 }
 
-// createUploadedFile создает объект File для загруженного файла
-func (c *CloudClient) createUploadedFile(createdFile *struct {
-	NewName string
-	NewPath string
-}, hash string, fileSize int64) *File {
-	return &File{
-		CloudStructureEntryBase: CloudStructureEntryBase{
-			FullPath: createdFile.NewPath,
-			Name:     createdFile.NewName,
-			Size:     NewSize(fileSize),
-			account:  c.Account,
-			client:   c,
-		},
-		Hash:                hash,
-		LastModifiedTimeUTC: time.Now().UTC(),
-	}
+// gzipReadCloser поток распаковки gzip, закрывающий исходное тело ответа
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.ReadCloser
 }
 
-// UploadFileFromStream загружает файл в облако из потока
-func (c *CloudClient) UploadFileFromStream(destFileName string, content io.Reader, destFolderPath string) (*File, error) {
-	if err := c.checkAuthorization(); err != nil {
+// newGzipReadCloser создает поток распаковки тела ответа в формате gzip
+func newGzipReadCloser(body io.ReadCloser) (*gzipReadCloser, error) {
+	reader, err := gzip.NewReader(body)
+	if err != nil {
 		return nil, err
 	}
+	return &gzipReadCloser{Reader: reader, body: body}, nil
+}
 
-	destFolderPath = c.getPathStartEndSlash(destFolderPath, true, true)
+// Close закрывает поток распаковки и тело ответа
+func (r *gzipReadCloser) Close() error {
+	_ = r.Reader.Close()
+	return r.body.Close()
+}
 
-	if err := c.validateUploadParams(destFileName, destFolderPath); err != nil {
+// openGetShardFile выполняет запрос файла к шарду Get начиная с указанного смещения
+func (c *CloudClient) openGetShardFile(ctx context.Context, filePath string, query url.Values, offset int64, ifModifiedSince time.Time) (*http.Response, error) {
+	downloadURL, err := c.getShardFileURL(filePath, query)
+	if err != nil {
 		return nil, err
 	}
 
-	contentBytes, err := readUploadContent(content)
+	req, err := c.newRequest(ctx, "GET", downloadURL, nil)
 	if err != nil {
 		return nil, err
 	}
-
-	fileSize := int64(len(contentBytes))
-	if err := c.validateUploadFileSize(fileSize); err != nil {
-		return nil, err
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	if !ifModifiedSince.IsZero() {
+		req.Header.Set("If-Modified-Since", ifModifiedSince.UTC().Format(http.TimeFormat))
 	}
 
-	uploadURL, err := c.getUploadShardURL()
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	hash, err := c.uploadToShard(uploadURL, contentBytes, fileSize)
-	if err != nil {
-		return nil, err
+	if resp.StatusCode == 422 {
+		drainAndClose(resp.Body)
+		return nil, &CloudClientError{
+			Message:   "Максимальный лимит размера скачивания составляет 4GB",
+			Source:    "sourceFilePath",
+			ErrorCode: ErrorCodeDownloadingSizeLimit,
+		}
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		drainAndClose(resp.Body)
+		return nil, &CloudClientError{
+			Message:   "Файл не существует в облаке",
+			Source:    "sourceFilePath",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		drainAndClose(resp.Body)
+		return nil, &CloudClientError{
+			Message:   "Файл не изменялся",
+			Source:    "sourceFilePath",
+			ErrorCode: ErrorCodeNotModified,
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		drainAndClose(resp.Body)
+		return nil, fmt.Errorf("скачивание не удалось: статус %d", resp.StatusCode)
 	}
 
-	createdFile, err := c.createFileOrFolder(true, destFolderPath+destFileName, hash, fileSize, false)
+	return resp, nil
+}
+
+// getShardFileURL формирует адрес файла на шарде Get по относительному пути
+func (c *CloudClient) getShardFileURL(filePath string, query url.Values) (string, error) {
+	shardURL, err := c.getShardURL("Get", func(shards *ShardsList) []*ShardInfo { return shards.Get })
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	return c.createUploadedFile(createdFile, hash, fileSize), nil
+	fileURL := shardURL + filePath
+	if len(query) > 0 {
+		fileURL += "?" + query.Encode()
+	}
+	return fileURL, nil
 }
 
-// DownloadFile скачивает файл из облака
-func (c *CloudClient) DownloadFile(sourceFilePath string) (io.ReadCloser, int64, error) {
-	if sourceFilePath == "" {
-		return nil, 0, &CloudClientError{
+// StatViaShard получает размер файла HEAD запросом к шарду Get без получения списка родительской папки.
+// Если шард не поддерживает HEAD или не возвращает размер, размер берется из метаданных файла
+func (c *CloudClient) StatViaShard(filePath string) (int64, error) {
+	if filePath == "" {
+		return 0, &CloudClientError{
 			Message:   "Путь к файлу не может быть пустым",
 			ErrorCode: ErrorCodePathNotExists,
 		}
 	}
 
-	sourceFilePath = strings.TrimPrefix(sourceFilePath, "/")
 	if err := c.checkAuthorization(); err != nil {
-		return nil, 0, err
+		return 0, err
 	}
 
-	shards, err := c.getShardsInfo()
+	fileURL, err := c.getShardFileURL(strings.TrimPrefix(filePath, "/"), nil)
 	if err != nil {
-		return nil, 0, err
+		return 0, err
 	}
 
-	if len(shards.Get) == 0 {
-		return nil, 0, fmt.Errorf("шарды Get не найдены")
-	}
-
-	shardURL := shards.Get[0].URL
-	req, err := http.NewRequestWithContext(c.cancelCtx, "GET", shardURL+sourceFilePath, nil)
+	req, err := c.newRequest(c.cancelCtx, "HEAD", fileURL, nil)
 	if err != nil {
-		return nil, 0, err
+		return 0, err
 	}
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, 0, err
-	}
-
-	if resp.StatusCode == 422 {
-		resp.Body.Close()
-		return nil, 0, &CloudClientError{
-			Message:   "Максимальный лимит размера скачивания составляет 4GB",
-			Source:    "sourceFilePath",
-			ErrorCode: ErrorCodeDownloadingSizeLimit,
-		}
+		return 0, err
 	}
+	drainAndClose(resp.Body)
 
-	if resp.StatusCode == http.StatusNotFound {
-		resp.Body.Close()
-		return nil, 0, &CloudClientError{
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return 0, &CloudClientError{
 			Message:   "Файл не существует в облаке",
-			Source:    "sourceFilePath",
+			Source:    "filePath",
 			ErrorCode: ErrorCodePathNotExists,
 		}
+	case resp.StatusCode == http.StatusOK && resp.ContentLength >= 0:
+		return resp.ContentLength, nil
 	}
 
-	contentLength := resp.ContentLength
-	if contentLength < 0 {
-		contentLength = 0
+	entry, err := c.getEntry(filePath)
+	if err != nil {
+		return 0, err
 	}
-
-	return resp.Body, contentLength, nil
+	if entry.IsFolder() {
+		return 0, &CloudClientError{
+			Message:   "Путь указывает на папку, а не на файл",
+			Source:    "filePath",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+	return entry.Size, nil
 }
 
 // DownloadItemsAsZIPArchive скачивает файлы и папки в ZIP архив по выбранным путям
 func (c *CloudClient) DownloadItemsAsZIPArchive(filesAndFoldersPaths []string) (io.ReadCloser, int64, error) {
+	return c.downloadZIPArchive(c.cancelCtx, filesAndFoldersPaths)
+}
+
+// downloadZIPArchive скачивает ZIP архив выбранных путей, выполняя запрос архива в контексте ctx
+func (c *CloudClient) downloadZIPArchive(ctx context.Context, filesAndFoldersPaths []string) (io.ReadCloser, int64, error) {
 	if err := c.checkAuthorization(); err != nil {
 		return nil, 0, err
 	}
 
+	if err := c.beginTransfer(); err != nil {
+		return nil, 0, err
+	}
+	stream, contentLength, err := c.openZIPArchive(ctx, filesAndFoldersPaths)
+	if err != nil {
+		c.transfers.Done()
+		return nil, 0, err
+	}
+	return c.trackTransfer(stream), contentLength, nil
+}
+
+// openZIPArchive создает ZIP архив по выбранным путям и открывает поток его скачивания
+func (c *CloudClient) openZIPArchive(ctx context.Context, filesAndFoldersPaths []string) (io.ReadCloser, int64, error) {
 	link, err := c.GetDirectLinkZIPArchive(filesAndFoldersPaths, "")
 	if err != nil {
 		return nil, 0, err
 	}
 
-	req, err := http.NewRequestWithContext(c.cancelCtx, "GET", link, nil)
+	req, err := c.newRequest(ctx, "GET", link, nil)
 	if err != nil {
 		return nil, 0, err
 	}
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		drainAndClose(resp.Body)
+		return nil, 0, fmt.Errorf("скачивание архива не удалось: статус %d", resp.StatusCode)
+	}
+
 	// Вычисление примерного размера
 	var contentLength int64
 	if len(filesAndFoldersPaths) > 0 {
@@ -1174,7 +2868,7 @@ func (c *CloudClient) DownloadItemsAsZIPArchive(filesAndFoldersPaths []string) (
 		}
 	}
 
-	return resp.Body, contentLength, nil
+	return c.throttleDownload(resp.Body), contentLength, nil
 }
 
 // DownloadItemsAsZIPArchiveToStream скачивает файлы и папки в ZIP архив в поток
@@ -1185,10 +2879,22 @@ func (c *CloudClient) DownloadItemsAsZIPArchiveToStream(filesAndFoldersPaths []s
 	}
 	defer stream.Close()
 
-	_, err = io.Copy(destStream, stream)
+	_, err = c.copyBuffer(destStream, stream)
 	return err
 }
 
+// copyBuffer копирует данные из src в dst, используя буфер из пула клиента
+func (c *CloudClient) copyBuffer(dst io.Writer, src io.Reader) (int64, error) {
+	pool := c.copyBufferPool
+	if pool == nil {
+		pool = defaultCopyBufferPool
+	}
+
+	buf := pool.Get().(*[]byte)
+	defer pool.Put(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
+
 // validateZipPaths проверяет валидность путей для ZIP архива
 func (c *CloudClient) validateZipPaths(filesAndFoldersPaths []string) error {
 	if len(filesAndFoldersPaths) == 0 {
@@ -1209,8 +2915,31 @@ func (c *CloudClient) validateZipPaths(filesAndFoldersPaths []string) error {
 	return nil
 }
 
-// prepareZipArchiveName подготавливает имя ZIP архива
-func prepareZipArchiveName(destZipArchiveName string) string {
+// zipArchiveNameReplacer заменяет в имени ZIP архива символы, недопустимые в именах файлов
+// и ломающие заголовок Content-Disposition
+var zipArchiveNameReplacer = strings.NewReplacer(`"`, "_", "<", "_", ">", "_", ":", "_", "|", "_", "?", "_", "*", "_")
+
+// prepareZipArchiveName подготавливает имя ZIP архива: пустое имя заменяется текущим временем UNIX,
+// кавычки, управляющие и другие недопустимые в именах файлов символы заменяются на "_", добавляется
+// расширение .zip. Имена с разделителями пути отклоняются
+func prepareZipArchiveName(destZipArchiveName string) (string, error) {
+	if strings.ContainsAny(destZipArchiveName, `/\`) {
+		return "", &CloudClientError{
+			Message:   "Имя архива не должно содержать разделители пути",
+			Source:    "destZipArchiveName",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	destZipArchiveName = zipArchiveNameReplacer.Replace(destZipArchiveName)
+	destZipArchiveName = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, destZipArchiveName)
+	destZipArchiveName = strings.TrimSpace(destZipArchiveName)
+
 	if destZipArchiveName == "" {
 		destZipArchiveName = fmt.Sprintf("%d", time.Now().Unix())
 	}
@@ -1218,7 +2947,7 @@ func prepareZipArchiveName(destZipArchiveName string) string {
 	if !strings.HasSuffix(strings.ToLower(destZipArchiveName), ".zip") {
 		destZipArchiveName += ".zip"
 	}
-	return destZipArchiveName
+	return destZipArchiveName, nil
 }
 
 // validateCommonPath проверяет, что все пути имеют общий родительский путь
@@ -1238,7 +2967,7 @@ func (c *CloudClient) validateCommonPath(filesAndFoldersPaths []string) ([]strin
 				ErrorCode: ErrorCodeDifferentParentPaths,
 			}
 		}
-		processedPaths[i] = fmt.Sprintf(`"%s"`, c.getPathStartEndSlash(path, true, false))
+		processedPaths[i] = c.getPathStartEndSlash(path, true, false)
 	}
 
 	return processedPaths, nil
@@ -1246,9 +2975,14 @@ func (c *CloudClient) validateCommonPath(filesAndFoldersPaths []string) ([]strin
 
 // createZipArchiveRequest создает запрос для создания ZIP архива
 func (c *CloudClient) createZipArchiveRequest(processedPaths []string, destZipArchiveName string) (*http.Request, error) {
-	pathsStr := fmt.Sprintf("[%s]", strings.Join(processedPaths, ","))
+	// Пути могут содержать кавычки и обратные слэши, поэтому список кодируется как JSON массив
+	homeList, err := json.Marshal(processedPaths)
+	if err != nil {
+		return nil, err
+	}
+
 	values := map[string]interface{}{
-		"home_list": pathsStr,
+		"home_list": string(homeList),
 		"name":      destZipArchiveName,
 		"api":       2,
 		"token":     c.Account.getAuthToken(),
@@ -1260,22 +2994,16 @@ func (c *CloudClient) createZipArchiveRequest(processedPaths []string, destZipAr
 		formData.Set(k, fmt.Sprintf("%v", v))
 	}
 
-	req, err := http.NewRequest("POST", BaseMailRuCloud+CreateZipArchive, strings.NewReader(formData.Encode()))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", UserAgent)
-	return req, nil
+	return c.newFormRequest(context.Background(), c.Account.cloudURL()+CreateZipArchive, formData)
 }
 
 // executeZipArchiveRequest выполняет запрос создания ZIP архива
 func (c *CloudClient) executeZipArchiveRequest(req *http.Request) (string, error) {
-	resp, err := c.Account.getHttpClient().Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
+	defer drainAndClose(resp.Body)
 
 	if resp.StatusCode == 422 {
 		return "", &CloudClientError{
@@ -1290,7 +3018,7 @@ func (c *CloudClient) executeZipArchiveRequest(req *http.Request) (string, error
 	}
 
 	var directLink string
-	if err := deserializeJSON(body, &directLink); err != nil {
+	if err := c.Account.decodeResponse(body, &directLink); err != nil {
 		return "", err
 	}
 
@@ -1307,7 +3035,10 @@ func (c *CloudClient) GetDirectLinkZIPArchive(filesAndFoldersPaths []string, des
 		return "", err
 	}
 
-	destZipArchiveName = prepareZipArchiveName(destZipArchiveName)
+	destZipArchiveName, err := prepareZipArchiveName(destZipArchiveName)
+	if err != nil {
+		return "", err
+	}
 
 	processedPaths, err := c.validateCommonPath(filesAndFoldersPaths)
 	if err != nil {
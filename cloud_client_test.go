@@ -1,8 +1,23 @@
 package mailrucloud
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -203,8 +218,8 @@ func TestRemove(t *testing.T) {
 
 	// Проверка, что папка удалена
 	result, err := testClient.GetFolder(folder.FullPath)
-	require.NoError(t, err)
 	assert.Nil(t, result)
+	assert.True(t, hasErrorCode(err, ErrorCodePathNotExists))
 }
 
 func TestRename(t *testing.T) {
@@ -438,6 +453,1619 @@ func TestGetItems(t *testing.T) {
 
 	// Тест получения несуществующей папки
 	result, err = testClient.GetFolder(TestFolderPath + "/nonexistent")
-	require.NoError(t, err)
 	assert.Nil(t, result)
+	assert.True(t, hasErrorCode(err, ErrorCodePathNotExists))
+}
+
+func TestSetDefaultHeaders(t *testing.T) {
+	client := &CloudClient{Account: NewAccount("", "")}
+	client.SetDefaultHeaders(map[string][]string{
+		"X-Request-Id": {"42"},
+		"User-Agent":   {"custom-agent"},
+		"Cookie":       {"session=forged"},
+	})
+
+	req, err := client.newRequest(context.Background(), "GET", BaseMailRuCloud, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "42", req.Header.Get("X-Request-Id"))
+	assert.Equal(t, "custom-agent", req.Header.Get("User-Agent"))
+	assert.Empty(t, req.Header.Get("Cookie"))
+
+	req, err = client.Account.newRequest(context.Background(), "GET", BaseMailRuCloud+AuthTokenURL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "42", req.Header.Get("X-Request-Id"))
+	assert.Equal(t, "custom-agent", req.Header.Get("User-Agent"))
+	assert.Empty(t, req.Header.Get("Cookie"))
+}
+
+// onlyReader скрывает WriterTo источника, чтобы копирование использовало буфер
+type onlyReader struct {
+	io.Reader
+}
+
+// onlyWriter скрывает ReaderFrom приемника, чтобы копирование использовало буфер
+type onlyWriter struct {
+	io.Writer
+}
+
+func BenchmarkCopyBuffer(b *testing.B) {
+	data := bytes.Repeat([]byte{1}, 16*1024*1024)
+
+	b.Run("io.Copy", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = io.Copy(onlyWriter{io.Discard}, onlyReader{bytes.NewReader(data)})
+		}
+	})
+
+	for _, size := range []int{defaultCopyBufferSize, 1024 * 1024} {
+		client := &CloudClient{}
+		WithCopyBufferSize(size)(client)
+		b.Run(fmt.Sprintf("pool-%dKB", size/1024), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = client.copyBuffer(onlyWriter{io.Discard}, onlyReader{bytes.NewReader(data)})
+			}
+		})
+	}
+}
+
+func TestUploadToShardRetryRewindsContent(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`"HASH"`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	WithUploadRetries(2)(client)
+
+	content := strings.NewReader("prefix:payload")
+	_, err := content.Seek(int64(len("prefix:")), io.SeekStart)
+	require.NoError(t, err)
+
+	hash, err := client.uploadToShard(server.URL, "/file.txt", content, int64(len("payload")))
+	require.NoError(t, err)
+	assert.Equal(t, "HASH", hash)
+	assert.Equal(t, []string{"payload", "payload"}, received)
+}
+
+func TestUploadRetryLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var log bytes.Buffer
+	client := newTestClient(server)
+	client.Account.Logger = slog.New(slog.NewTextHandler(&log, nil))
+	WithUploadRetries(1)(client)
+
+	_, err := client.uploadToShard(server.URL, "/docs/file.txt", strings.NewReader("payload"), int64(len("payload")))
+	require.Error(t, err)
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `msg="повторная попытка после ошибки" operation=upload path=/docs/file.txt attempt=1`)
+	assert.Contains(t, lines[0], "статусом 503")
+	assert.Contains(t, lines[1], `msg="операция не удалась после всех попыток" operation=upload path=/docs/file.txt attempts=2`)
+}
+
+func TestHas2GBUploadSizeLimitFallback(t *testing.T) {
+	account := NewAccount("user@mail.ru", "password")
+	assert.True(t, account.Has2GBUploadSizeLimit())
+
+	account.fallbackUploadSizeLimit = uploadSizeLimit32GB
+	assert.False(t, account.Has2GBUploadSizeLimit())
+
+	account.ActivatedTariffs = []*Rate{{ID: "ZERO"}}
+	assert.True(t, account.Has2GBUploadSizeLimit())
+}
+
+func TestParseHomeError(t *testing.T) {
+	assert.Equal(t, "exists", parseHomeError([]byte(`{"email":"user@mail.ru","body":{"home":{"error":"exists","value":"/a.txt"}},"status":400}`)))
+	assert.Equal(t, "", parseHomeError([]byte(`<html>Bad Request</html>`)))
+}
+
+func TestUploadToShardChunkVerification(t *testing.T) {
+	const validHash = "7061796C6F616400000000000000000000000000"
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		attempts++
+		if attempts == 1 {
+			_, _ = w.Write([]byte(`"0000000000000000000000000000000000000000"`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, "%q", validHash)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	WithChunkVerification(true)(client)
+
+	hash, err := client.uploadToShard(server.URL, "/file.txt", strings.NewReader("payload"), int64(len("payload")))
+	require.NoError(t, err)
+	assert.Equal(t, validHash, hash)
+	assert.Equal(t, 2, attempts)
+
+	_, err = client.uploadToShard(server.URL, "/file.txt", io.MultiReader(strings.NewReader("other")), int64(len("other")))
+	assert.True(t, hasErrorCode(err, ErrorCodeUploadFailed))
+}
+
+func TestUploadToShardFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("<html>Forbidden</html>"))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+
+	hash, err := client.uploadToShard(server.URL, "/file.txt", strings.NewReader("payload"), int64(len("payload")))
+	assert.Empty(t, hash)
+	require.True(t, hasErrorCode(err, ErrorCodeUploadFailed))
+	assert.Equal(t, server.URL, err.(*CloudClientError).Source)
+}
+
+// newHTTP2TestClient создает клиент для TLS сервера с поддержкой HTTP/2 и указанными опциями аккаунта
+func newHTTP2TestClient(server *httptest.Server, opts ...AccountOption) *CloudClient {
+	account := NewAccount("user@mail.ru", "password", opts...)
+	account.transport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	account.initHttpClient(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CloudClient{Account: account, cancelCtx: ctx, cancelToken: cancel}
+}
+
+func TestHTTP2Option(t *testing.T) {
+	protocols := make(chan int, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		protocols <- r.ProtoMajor
+		_, _ = w.Write([]byte(`"HASH"`))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for _, tc := range []struct {
+		opts  []AccountOption
+		proto int
+	}{
+		{nil, 2},
+		{[]AccountOption{WithHTTP2(true)}, 2},
+		{[]AccountOption{WithHTTP2(false), WithMaxConnsPerHost(2), WithIdleConnTimeout(time.Second)}, 1},
+	} {
+		client := newHTTP2TestClient(server, tc.opts...)
+		_, err := client.uploadToShard(server.URL, "/file.txt", strings.NewReader("payload"), int64(len("payload")))
+		require.NoError(t, err)
+		assert.Equal(t, tc.proto, <-protocols)
+		client.Account.transport.CloseIdleConnections()
+	}
+}
+
+func TestTLSPinning(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`"HASH"`))
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		pin     []byte
+		matches bool
+	}{
+		{SPKIPin(server.Certificate()), true},
+		{make([]byte, 32), false},
+	} {
+		client := newHTTP2TestClient(server)
+		WithTLSPinning([][]byte{tc.pin})(client.Account)
+
+		_, err := client.uploadToShard(server.URL, "/file.txt", strings.NewReader("payload"), int64(len("payload")))
+		if tc.matches {
+			assert.NoError(t, err)
+		} else {
+			assert.True(t, hasErrorCode(err, ErrorCodeCertificatePinMismatch), "error: %v", err)
+		}
+	}
+}
+
+// BenchmarkConcurrentSmallUploads сравнивает параллельную загрузку небольших файлов по HTTP/1.1 и HTTP/2
+func BenchmarkConcurrentSmallUploads(b *testing.B) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`"HASH"`))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for _, enabled := range []bool{false, true} {
+		client := newHTTP2TestClient(server, WithHTTP2(enabled))
+		b.Run(fmt.Sprintf("http2=%t", enabled), func(b *testing.B) {
+			b.SetParallelism(16)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_, _ = client.uploadToShard(server.URL, "/file.txt", strings.NewReader("payload"), int64(len("payload")))
+				}
+			})
+		})
+		client.Account.transport.CloseIdleConnections()
+	}
+}
+
+func TestConnectionReuse(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusForbidden} {
+		var connections int32
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.ReadAll(r.Body)
+			w.WriteHeader(status)
+			if status != http.StatusOK {
+				_, _ = io.WriteString(w, strings.Repeat("error page ", 1024))
+				return
+			}
+			_, _ = w.Write([]byte(`"HASH"`))
+		}))
+		server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&connections, 1)
+			}
+		}
+		server.Start()
+
+		account := NewAccount("user@mail.ru", "password", WithMaxIdleConnsPerHost(4))
+		account.initHttpClient(server.URL)
+		ctx, cancel := context.WithCancel(context.Background())
+		client := &CloudClient{Account: account, cancelCtx: ctx, cancelToken: cancel}
+
+		for i := 0; i < 20; i++ {
+			_, err := client.uploadToShard(server.URL, "/file.txt", strings.NewReader("payload"), int64(len("payload")))
+			assert.Equal(t, status != http.StatusOK, err != nil)
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&connections), "status %d", status)
+
+		cancel()
+		account.transport.CloseIdleConnections()
+		server.Close()
+	}
+}
+
+func TestUploadFromHTTPResponseBody(t *testing.T) {
+	payload := strings.Repeat("streamed content ", 1024)
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(payload)))
+		_, _ = io.WriteString(w, payload)
+	}))
+	defer source.Close()
+
+	var received string
+	var receivedLength int64
+	shard := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		receivedLength = r.ContentLength
+		_, _ = w.Write([]byte(`"HASH"`))
+	}))
+	defer shard.Close()
+
+	resp, err := http.Get(source.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	options := newUploadOptions([]UploadOption{WithContentLength(resp.ContentLength)})
+	content, size, err := prepareUploadContent(resp.Body, options.contentLength)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(payload)), size)
+	assert.Equal(t, resp.Body, content, "тело ответа должно передаваться без буферизации")
+
+	client := newTestClient(shard)
+	WithUploadRetries(3)(client)
+	hash, err := client.uploadToShard(shard.URL, "/file.txt", content, size)
+	require.NoError(t, err)
+	assert.Equal(t, "HASH", hash)
+	assert.Equal(t, payload, received)
+	assert.Equal(t, size, receivedLength)
+}
+
+func TestAccountEndpoints(t *testing.T) {
+	account := NewAccount("user@Company.ru", "password")
+	assert.Equal(t, BaseMailRuCloud, account.cloudURL())
+	assert.Equal(t, "mail.ru", account.getAuthDomain())
+	assert.Equal(t, PublicLink+"abc/def", account.buildPublicLink("abc/def"))
+
+	account = NewAccount("user@Company.ru", "password", WithAuthDomainFromEmail())
+	assert.Equal(t, "company.ru", account.getAuthDomain())
+
+	account = NewAccount("user@company.ru", "password", WithBusinessAccount())
+	assert.Equal(t, "company.ru", account.getAuthDomain())
+
+	account = NewAccount("user@company.ru", "password", WithBusinessAccount(), WithAuthDomain("corp.ru"))
+	assert.Equal(t, BaseMailRuBizCloud, account.cloudURL())
+	assert.Equal(t, BaseMailRuAuth, account.authURL())
+	assert.Equal(t, "corp.ru", account.getAuthDomain())
+	assert.Equal(t, BaseMailRuBizCloud+"/public/abc/def", account.buildPublicLink("abc/def"))
+}
+
+func TestNewAccountWithToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("sdcs")
+		if r.URL.Query().Get("token") != "valid" || err != nil || cookie.Value != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"bytes_total":8192,"bytes_used":1024}`))
+	}))
+	defer server.Close()
+
+	cookies := []*http.Cookie{{Name: "sdcs", Value: "session"}}
+
+	account := NewAccountWithToken("user@mail.ru", "valid", cookies, WithEndpoints(server.URL, server.URL))
+	assert.Equal(t, "valid", account.Token())
+	require.Len(t, account.Cookies(), 1)
+	_, err := newCloudClientWithTokenAccount(account)
+	require.NoError(t, err)
+
+	// Клиент сессии передает cookies авторизации в запросах, которые библиотека не поддерживает
+	resp, err := account.HTTPClient().Get(server.URL + "/other?token=valid")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	account = NewAccountWithToken("user@mail.ru", "expired", cookies, WithEndpoints(server.URL, server.URL))
+	_, err = newCloudClientWithTokenAccount(account)
+	var authErr *NotAuthorizedError
+	assert.ErrorAs(t, err, &authErr)
+}
+
+func TestDownloadFileGzipEncoding(t *testing.T) {
+	content := strings.Repeat("сжимаемое содержимое ", 200)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte(content))
+	require.NoError(t, gz.Close())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get/file.txt", func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Accept-Encoding"), "gzip")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", fmt.Sprint(compressed.Len()))
+		_, _ = w.Write(compressed.Bytes())
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	for _, explicit := range []bool{false, true} {
+		if explicit {
+			// Явный Accept-Encoding отключает автоматическую распаковку в транспорте
+			client.SetDefaultHeaders(http.Header{"Accept-Encoding": {"gzip"}})
+		}
+
+		stream, length, err := client.DownloadFile("/file.txt")
+		require.NoError(t, err)
+		data, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+
+		assert.Equal(t, content, string(data), "explicit=%t", explicit)
+		assert.Equal(t, int64(-1), length, "explicit=%t", explicit)
+	}
+}
+
+func TestDownloadFileIfModifiedSince(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get/file.txt", func(w http.ResponseWriter, r *http.Request) {
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	stream, _, err := client.DownloadFileIfModifiedSince("/file.txt", modified)
+	assert.Nil(t, stream)
+	assert.True(t, hasErrorCode(err, ErrorCodeNotModified))
+
+	for _, since := range []time.Time{modified.Add(-time.Hour), {}} {
+		stream, _, err = client.DownloadFileIfModifiedSince("/file.txt", since)
+		require.NoError(t, err)
+		data, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		assert.Equal(t, "content", string(data))
+	}
+}
+
+func TestRenamePreservesPublicLink(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		weblink    string
+		republish  bool
+		expected   string
+		publishing bool
+	}{
+		{"ссылка сохранилась", "abc/def", false, "abc/def", false},
+		{"ссылка удалена", "", false, "", false},
+		{"повторная публикация", "", true, "new/link", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			renamed := false
+			published := false
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+				name, weblink := "old.txt", "abc/def"
+				if renamed {
+					name, weblink = "new.txt", tc.weblink
+				}
+				fmt.Fprintf(w, `{"body":{"type":"folder","home":"/","list":[{"type":"file","name":%q,"home":"/%s","weblink":%q}]}}`, name, name, weblink)
+			})
+			mux.HandleFunc("/api/v2/file/rename", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "new.txt", r.FormValue("name"))
+				renamed = true
+				_, _ = w.Write([]byte(`{"body":"/new.txt"}`))
+			})
+			mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"body":{"type":"file","name":"new.txt","home":"/new.txt","weblink":%q}}`, tc.weblink)
+			})
+			mux.HandleFunc("/api/v2/file/publish", func(w http.ResponseWriter, r *http.Request) {
+				published = true
+				_, _ = w.Write([]byte(`{"body":"new/link"}`))
+			})
+			client, _ := newFakeCloud(t, mux)
+
+			item, err := client.RenameWithOptions("/old.txt", "new", RenameOptions{Republish: tc.republish})
+			require.NoError(t, err)
+			assert.Equal(t, "/new.txt", item.FullPath)
+			assert.Equal(t, client.Account.buildPublicLink(tc.expected), item.PublicLink)
+			assert.Equal(t, tc.publishing, published)
+		})
+	}
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	ctx := context.Background()
+	req, err := NewAccount("user@mail.ru", "password").newRequest(ctx, "GET", "https://cloud.mail.ru", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ru", req.Header.Get("Accept-Language"))
+
+	req, err = NewAccount("user@mail.ru", "password", WithAcceptLanguage("en-US,en;q=0.9")).newRequest(ctx, "GET", "https://cloud.mail.ru", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "en-US,en;q=0.9", req.Header.Get("Accept-Language"))
+
+	req, err = NewAccount("user@mail.ru", "password", WithAcceptLanguage("")).newRequest(ctx, "GET", "https://cloud.mail.ru", nil)
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Values("Accept-Language"))
+}
+
+func TestNewCloudClientNoCheck(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte(`{"bytes_total":8192,"bytes_used":1024}`))
+	}))
+	defer server.Close()
+
+	account := NewAccountWithToken("user@mail.ru", "token", nil, WithEndpoints(server.URL, server.URL))
+	_, err := NewCloudClient(account)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	for i := 0; i < 3; i++ {
+		client, err := NewCloudClientNoCheck(account, WithDownloadResume(true))
+		require.NoError(t, err)
+		assert.Same(t, account, client.Account)
+		assert.True(t, client.downloadResume)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	_, err = NewCloudClientNoCheck(nil)
+	assert.Error(t, err)
+}
+
+func TestUploadInsufficientStorage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusInsufficientStorage)
+	})
+	client, server := newFakeCloud(t, mux)
+
+	_, err := client.uploadToShard(server.URL+"/upload/", "/file.txt", strings.NewReader("payload"), int64(len("payload")))
+	assert.True(t, hasErrorCode(err, ErrorCodeInsufficientStorage))
+
+	err = client.insufficientStorageError("/big.bin", 8192*1024*1024)
+	require.True(t, hasErrorCode(err, ErrorCodeInsufficientStorage))
+	assert.Contains(t, err.Error(), "на 1073741824 байт")
+}
+
+func TestGetFolderFiltered(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","home":"/docs","list":[{"type":"file","name":"a.txt","home":"/docs/a.txt"},{"type":"folder","name":"sub","home":"/docs/sub"}]}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	folder, err := client.GetFolderOnlyFiles("/docs")
+	require.NoError(t, err)
+	require.Len(t, folder.Items, 1)
+	assert.Equal(t, "/docs/a.txt", folder.Items[0].Home)
+
+	folder, err = client.GetFolderOnlyFolders("/docs")
+	require.NoError(t, err)
+	require.Len(t, folder.Items, 1)
+	assert.Equal(t, "/docs/sub", folder.Items[0].Home)
+}
+
+func TestEnsureFolder(t *testing.T) {
+	existing := map[string]bool{"/docs": true}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder/add", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "strict", r.PostForm.Get("conflict"))
+		home := strings.TrimSuffix(r.PostForm.Get("home"), "/")
+		if existing[home] {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"body":{"home":{"error":"exists"}}}`)
+			return
+		}
+		existing[home] = true
+		_, _ = io.WriteString(w, `{"body":"`+home+`"}`)
+	})
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		home := r.URL.Query().Get("home")
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","name":"docs","home":"`+home+`"}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	folder, err := client.EnsureFolder("/docs")
+	require.NoError(t, err)
+	assert.Equal(t, "/docs", folder.FullPath)
+
+	folder, err = client.EnsureFolder("/new")
+	require.NoError(t, err)
+	assert.Equal(t, "/new", folder.FullPath)
+}
+
+func TestDeserializeJSON(t *testing.T) {
+	var value string
+	require.NoError(t, deserializeJSON([]byte(`{"body":"/a"}`), &value))
+	assert.Equal(t, "/a", value)
+	require.NoError(t, deserializeJSON([]byte(`"/b"`), &value))
+	assert.Equal(t, "/b", value)
+
+	err := deserializeJSON([]byte(`<html>maintenance</html>`), &value)
+	var decodeErr *ResponseDecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, "<html>maintenance</html>", decodeErr.Snippet)
+}
+
+func TestSetResponseDecoder(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"result":{"type":"file","name":"a.txt","home":"/a.txt"}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+	client.Account.SetResponseDecoder(func(data []byte, target interface{}) error {
+		var resp struct {
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		return json.Unmarshal(resp.Result, target)
+	})
+
+	entry, err := client.Stat("/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", entry.Name)
+}
+
+func TestLoginPageResponse(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(w, `<!DOCTYPE html><html><body><form action="/login">Войти</form></body></html>`)
+	})
+	mux.HandleFunc("/api/v2/tokens/csrf", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"token":"new-token"}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+	client.Account.authorized = true
+
+	var states []AuthState
+	client.Account.OnAuthStateChanged(func(state AuthState) {
+		states = append(states, state)
+	})
+
+	_, err := client.Stat("/a.txt")
+	var authErr *NotAuthorizedError
+	require.ErrorAs(t, err, &authErr)
+	assert.Equal(t, "new-token", client.Account.Token())
+	assert.Equal(t, []AuthState{AuthStateLost, AuthStateRefreshed}, states)
+}
+
+func TestLoginPageResponseConcurrentWalk(t *testing.T) {
+	var csrfCalls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		home := r.URL.Query().Get("home")
+		if home == "/" {
+			var items []string
+			for i := 0; i < 8; i++ {
+				items = append(items, fmt.Sprintf(`{"type":"folder","name":"f%[1]d","home":"/f%[1]d"}`, i))
+			}
+			_, _ = fmt.Fprintf(w, `{"body":{"type":"folder","home":"/","list":[%s]}}`, strings.Join(items, ","))
+			return
+		}
+		if r.URL.Query().Get("token") != "new-token" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = io.WriteString(w, `<html><body>Войти</body></html>`)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"body":{"type":"folder","home":%q,"list":[]}}`, strings.TrimSuffix(home, "/"))
+	})
+	mux.HandleFunc("/api/v2/tokens/csrf", func(w http.ResponseWriter, r *http.Request) {
+		csrfCalls.Add(1)
+		_, _ = io.WriteString(w, `{"body":{"token":"new-token"}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+	client.Account.OnAuthStateChanged(func(state AuthState) {})
+
+	_, err := client.ListAllFiles("/")
+	var authErr *NotAuthorizedError
+	require.ErrorAs(t, err, &authErr)
+	assert.Equal(t, "new-token", client.Account.Token())
+	assert.GreaterOrEqual(t, csrfCalls.Load(), int32(1))
+}
+
+func TestRefreshTokenSingleFlight(t *testing.T) {
+	var csrfCalls atomic.Int32
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/tokens/csrf", func(w http.ResponseWriter, r *http.Request) {
+		if csrfCalls.Add(1) == 1 {
+			close(entered)
+		}
+		<-release
+		_, _ = io.WriteString(w, `{"body":{"token":"new-token"}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs <- client.Account.RefreshToken()
+	}()
+	<-entered
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- client.Account.RefreshTokenContext(context.Background())
+		}()
+	}
+	// Ожидающие вызовы должны присоединиться к уже выполняющемуся обновлению
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), csrfCalls.Load())
+	assert.Equal(t, "new-token", client.Account.Token())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.Account.refreshMu.Lock()
+	client.Account.refreshing = &authRefresh{done: make(chan struct{})}
+	client.Account.refreshMu.Unlock()
+	assert.ErrorIs(t, client.Account.RefreshTokenContext(ctx), context.Canceled)
+}
+
+func TestFolderRemoveMarksRemoved(t *testing.T) {
+	var folderRequests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		folderRequests++
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","name":"docs","home":"/docs","list":[{"type":"file","name":"a.txt","home":"/docs/a.txt"}]}}`)
+	})
+	mux.HandleFunc("/api/v2/file/remove", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":"/docs"}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	folder, err := client.GetFolder("/docs")
+	require.NoError(t, err)
+	require.NoError(t, folder.Remove())
+	assert.Equal(t, 1, folderRequests)
+
+	assert.Empty(t, folder.GetFiles())
+	_, err = folder.ListFiles()
+	assert.True(t, hasErrorCode(err, ErrorCodePathNotExists))
+	_, err = folder.Rename("other")
+	assert.True(t, hasErrorCode(err, ErrorCodePathNotExists))
+	assert.Equal(t, 1, folderRequests)
+}
+
+func TestCreateFolderWithConflict(t *testing.T) {
+	var conflicts []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder/add", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		conflicts = append(conflicts, r.PostForm.Get("conflict"))
+		_, _ = io.WriteString(w, `{"body":"/docs"}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	for _, mode := range []ConflictMode{ConflictModeRename, ConflictModeStrict, ConflictModeRewrite} {
+		folder, err := client.CreateFolderWithConflict("/docs", mode)
+		require.NoError(t, err)
+		assert.Equal(t, "/docs", folder.FullPath)
+	}
+	assert.Equal(t, []string{"rename", "strict", "rewrite"}, conflicts)
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	var active, peak int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&active, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","home":"/","list":[]}}`)
+	})
+	mux.HandleFunc("/get/file.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	})
+	client, _ := newFakeCloud(t, mux)
+	WithMaxConcurrentRequests(2)(client)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.GetFolder("/")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+
+	// Открытые потоки скачивания занимают места до закрытия
+	first, _, err := client.DownloadFile("/file.txt")
+	require.NoError(t, err)
+	second, _, err := client.DownloadFile("/file.txt")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.GetFolderContext(ctx, "/")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, first.Close())
+	require.NoError(t, second.Close())
+	_, err = client.GetFolder("/")
+	assert.NoError(t, err)
+}
+
+func TestEnsureFolderConcurrent(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		created int
+		lookups int
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder/add", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if created > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"body":{"home":{"error":"exists"}}}`)
+			return
+		}
+		created++
+		_, _ = io.WriteString(w, `{"body":"/jobs/2024/out"}`)
+	})
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Созданная папка появляется в метаданных с задержкой
+		lookups++
+		if lookups <= 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","name":"out","home":"/jobs/2024/out"}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	paths := make([]string, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			folder, err := client.EnsureFolder("/jobs/2024/out")
+			errs[i] = err
+			if err == nil {
+				paths[i] = folder.FullPath
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < workers; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "/jobs/2024/out", paths[i])
+	}
+	assert.Equal(t, 1, created)
+}
+
+func TestRestoreFromHistoryReturnsServerMetadata(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file/add", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "/docs/restored.txt", r.PostForm.Get("home"))
+		_, _ = io.WriteString(w, `{"body":"/docs/restored (1).txt"}`)
+	})
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/docs/restored (1).txt", r.URL.Query().Get("home"))
+		_, _ = io.WriteString(w, `{"body":{"type":"file","name":"restored (1).txt","home":"/docs/restored (1).txt","size":2048,"hash":"SERVERHASH","mtime":1700000000}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	history := &History{Revision: 3, Hash: "HISTORYHASH", SizeBytes: 1024, Size: NewSize(1024)}
+	file, err := client.restoreFromHistory("/docs/report.txt", history, false, "restored")
+	require.NoError(t, err)
+	assert.Equal(t, "/docs/restored (1).txt", file.FullPath)
+	assert.Equal(t, "restored (1).txt", file.Name)
+	assert.Equal(t, "SERVERHASH", file.Hash)
+	assert.Equal(t, int64(2048), file.Size.DefaultValue)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), file.LastModifiedTimeUTC)
+}
+
+func TestExistsMany(t *testing.T) {
+	var requests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		home := r.URL.Query().Get("home")
+		requests = append(requests, home)
+		if home != "/docs/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","home":"/docs","list":[{"type":"file","name":"a.txt","home":"/docs/a.txt"},{"type":"folder","name":"sub","home":"/docs/sub"}]}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	result, err := client.ExistsMany([]string{"/docs/a.txt", "/docs/sub", "/docs/b.txt", "/missing/c.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"/docs/a.txt":    true,
+		"/docs/sub":      true,
+		"/docs/b.txt":    false,
+		"/missing/c.txt": false,
+	}, result)
+	assert.Len(t, requests, 2)
+}
+
+func TestUploadIfNewer(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "doc.txt")
+	require.NoError(t, os.WriteFile(source, []byte("local content"), 0o644))
+	localTime := time.Unix(1700000000, 0)
+	require.NoError(t, os.Chtimes(source, localTime, localTime))
+
+	remoteMtime := localTime.Add(-time.Minute).Unix()
+	var uploads int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"body":{"type":"file","name":"doc.txt","home":"/docs/doc.txt","mtime":%d}}`, remoteMtime)
+	})
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","home":"/docs","list":[{"type":"file","name":"doc.txt","home":"/docs/doc.txt"}]}}`)
+	})
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+		_, _ = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, `"HASH"`)
+	})
+	mux.HandleFunc("/api/v2/file/add", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "rewrite", r.PostForm.Get("conflict"))
+		_, _ = io.WriteString(w, `{"body":"/docs/doc.txt"}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	_, uploaded, err := client.UploadIfNewer("", source, "/docs", WithClockSkewTolerance(2*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, uploaded)
+	assert.Equal(t, 0, uploads)
+
+	_, uploaded, err = client.UploadIfNewer("", source, "/docs")
+	require.NoError(t, err)
+	assert.True(t, uploaded)
+	assert.Equal(t, 1, uploads)
+
+	remoteMtime = localTime.Add(time.Minute).Unix()
+	_, uploaded, err = client.UploadIfNewer("", source, "/docs")
+	require.NoError(t, err)
+	assert.False(t, uploaded)
+}
+
+type staticCredentials struct {
+	calls    int
+	email    string
+	password string
+	err      error
+}
+
+func (p *staticCredentials) Credentials() (string, string, error) {
+	p.calls++
+	return p.email, p.password, p.err
+}
+
+func TestCredentialProvider(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	provider := &staticCredentials{err: errors.New("secret unavailable")}
+	account := NewAccount("", "", WithEndpoints(server.URL, server.URL), WithCredentialProvider(provider))
+	err := account.Login()
+	var authErr *NotAuthorizedError
+	require.ErrorAs(t, err, &authErr)
+	assert.Contains(t, authErr.Message, "secret unavailable")
+
+	provider.err = nil
+	provider.email = "rotated@mail.ru"
+	provider.password = "new-password"
+	_ = account.Login()
+	assert.Equal(t, 2, provider.calls)
+	assert.Equal(t, "rotated@mail.ru", account.Email)
+	assert.Equal(t, "new-password", account.Password)
+}
+
+func TestInvites(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder/invites", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"list":[{"invite_token":"abc","name":"Team","home":"/Team","access":"read_only","owner":{"email":"owner@mail.ru"}}]}}`)
+	})
+	mux.HandleFunc("/api/v2/folder/mount", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("invite_token") != "abc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "/SharedTeam", r.PostForm.Get("home"))
+		_, _ = io.WriteString(w, `{"body":"/SharedTeam"}`)
+	})
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","name":"SharedTeam","home":"/SharedTeam","list":[]}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	invites, err := client.ListInvites()
+	require.NoError(t, err)
+	require.Len(t, invites, 1)
+	assert.Equal(t, "abc", invites[0].ID)
+	assert.True(t, invites[0].ReadOnly())
+	assert.Equal(t, "owner@mail.ru", invites[0].Owner.Email)
+
+	folder, err := client.AcceptInvite("abc", "SharedTeam")
+	require.NoError(t, err)
+	assert.Equal(t, "/SharedTeam", folder.FullPath)
+
+	_, err = client.AcceptInvite("expired", "Old")
+	assert.True(t, hasErrorCode(err, ErrorCodeInviteExpired))
+}
+
+func TestGetFileOneTimeDirectLinkAs(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/tokens/download", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"token":"key"}}`)
+	})
+	client, server := newFakeCloud(t, mux)
+
+	link, err := client.GetFileOneTimeDirectLinkAs(client.Account.publicLinkPrefix()+"AbCd/file.bin", "Отчет 2024.pdf")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(link, server.URL+"/weblink/AbCd/file.bin?key="), link)
+	assert.True(t, strings.HasSuffix(link, "&filename="+url.QueryEscape("Отчет 2024.pdf")), link)
+
+	for _, name := range []string{"", "..", "a/b.txt", `a\b.txt`, "a\nb.txt"} {
+		_, err := client.GetFileOneTimeDirectLinkAs(client.Account.publicLinkPrefix()+"AbCd/file.bin", name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestGetRecentFiles(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder/recent", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		_, _ = io.WriteString(w, `{"body":{"list":[{"type":"file","name":"old.txt","home":"/old.txt","mtime":1000},{"type":"file","name":"new.txt","home":"/docs/new.txt","mtime":3000,"hash":"H","size":10}]}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	files, err := client.GetRecentFiles(2)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "/docs/new.txt", files[0].FullPath)
+	assert.Equal(t, "H", files[0].Hash)
+	assert.Equal(t, "/old.txt", files[1].FullPath)
+}
+
+func TestDiskUsagePercent(t *testing.T) {
+	usage := &DiskUsage{Total: NewSize(200), Used: NewSize(50), Free: NewSize(150)}
+	assert.InDelta(t, 25.0, usage.PercentUsed(), 1e-9)
+	assert.InDelta(t, 75.0, usage.PercentFree(), 1e-9)
+
+	empty := &DiskUsage{Total: NewSize(0), Used: NewSize(0), Free: NewSize(0)}
+	assert.Equal(t, 0.0, empty.PercentUsed())
+	assert.Equal(t, 0.0, empty.PercentFree())
+}
+
+func TestSizeJSONRoundTrip(t *testing.T) {
+	usage := &DiskUsage{Total: NewSize(8 << 30), Used: NewSize(1536), Free: NewSize(8<<30 - 1536)}
+	data, err := json.Marshal(usage)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Total":8589934592,"Used":1536,"Free":8589933056}`, string(data))
+
+	var decoded DiskUsage
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, usage, &decoded)
+	assert.Equal(t, StorageUnitKB, decoded.Used.NormalizedType)
+	assert.Equal(t, 1.5, decoded.Used.NormalizedValue)
+
+	history := &History{Name: "a.txt", Size: NewSize(2048), SizeBytes: 2048}
+	data, err = json.Marshal(history)
+	require.NoError(t, err)
+	var decodedHistory History
+	require.NoError(t, json.Unmarshal(data, &decodedHistory))
+	assert.Equal(t, history, &decodedHistory)
+
+	// Ответ API заполняет только SizeBytes
+	var apiHistory History
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"a.txt","size":4096}`), &apiHistory))
+	assert.Equal(t, int64(4096), apiHistory.SizeBytes)
+	assert.Nil(t, apiHistory.Size)
+
+	// Формат до появления MarshalJSON
+	var legacy Size
+	require.NoError(t, json.Unmarshal([]byte(`{"DefaultValue":1048576,"NormalizedValue":1,"NormalizedType":2}`), &legacy))
+	assert.Equal(t, *NewSize(1048576), legacy)
+	assert.Error(t, json.Unmarshal([]byte(`"1 MB"`), &legacy))
+}
+
+func TestClockOffset(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	offset, err := client.ClockOffset()
+	require.NoError(t, err)
+	assert.InDelta(t, float64(time.Hour), float64(offset), float64(2*time.Second))
+
+	serverTime, err := client.ServerTime()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), serverTime, 2*time.Second)
+	assert.Equal(t, 1, requests)
+}
+
+func TestPrepareZipArchiveName(t *testing.T) {
+	cases := map[string]string{
+		`Отчет "финальный"`: "Отчет _финальный_.zip",
+		"Фото 2024.ZIP":     "Фото 2024.ZIP",
+		"a\tb":              "a_b.zip",
+	}
+	for name, expected := range cases {
+		prepared, err := prepareZipArchiveName(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, prepared)
+	}
+
+	prepared, err := prepareZipArchiveName("")
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(prepared, ".zip"))
+
+	for _, name := range []string{"../archive", `dir\archive`} {
+		_, err := prepareZipArchiveName(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestZipArchiveHomeListEscaping(t *testing.T) {
+	client, _ := newFakeCloud(t, http.NewServeMux())
+	paths := []string{`/docs/say "hi".txt`, `/docs/{"a":1}.txt`, "/docs/Отчет.pdf"}
+
+	processed, err := client.validateCommonPath(paths)
+	require.NoError(t, err)
+	req, err := client.createZipArchiveRequest(processed, "archive.zip")
+	require.NoError(t, err)
+	require.NoError(t, req.ParseForm())
+
+	var homeList []string
+	require.NoError(t, json.Unmarshal([]byte(req.PostForm.Get("home_list")), &homeList))
+	assert.Equal(t, paths, homeList)
+}
+
+func TestUploadFileFromStreamToSkipsFolderLookup(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, `"7061796C6F616400000000000000000000000000"`)
+	})
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected folder lookup: %s", r.URL.Query().Get("home"))
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/api/v2/file/add", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "/docs/doc.txt", r.PostForm.Get("home"))
+		_, _ = io.WriteString(w, `{"body":"/docs/doc.txt"}`)
+	})
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"type":"file","name":"doc.txt","home":"/docs/doc.txt","size":7}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	folder := &Folder{CloudStructureEntryBase: CloudStructureEntryBase{Name: "docs", FullPath: "/docs", client: client}}
+	file, err := client.UploadFileFromStreamTo(folder, "doc.txt", strings.NewReader("payload"))
+	require.NoError(t, err)
+	assert.Equal(t, "/docs/doc.txt", file.FullPath)
+
+	_, err = client.UploadFileFromStreamTo(nil, "doc.txt", strings.NewReader("payload"))
+	assert.True(t, hasErrorCode(err, ErrorCodePathNotExists))
+
+	_, err = client.UploadFileFromStreamTo(folder, "bad:name.txt", strings.NewReader("payload"))
+	assert.True(t, hasErrorCode(err, ErrorCodeInvalidName))
+
+	folder.ReadOnly = true
+	_, err = client.UploadFileFromStreamTo(folder, "doc.txt", strings.NewReader("payload"))
+	assert.True(t, hasErrorCode(err, ErrorCodeAccessDenied))
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","home":"/docs","list":[{"type":"file","name":"a.txt","home":"/docs/a.txt"}]}}`)
+	})
+	mux.HandleFunc("/api/v2/file/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected mutating request: %s", r.URL.Path)
+	})
+	mux.HandleFunc("/api/v2/folder/add", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected mutating request: %s", r.URL.Path)
+	})
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected upload: %s", r.URL.Path)
+	})
+	client, _ := newFakeCloud(t, mux)
+	WithReadOnly(true)(client)
+
+	folder, err := client.GetFolder("/docs")
+	require.NoError(t, err)
+	assert.Len(t, folder.Items, 1)
+
+	_, err = client.CreateFolder("/docs/new")
+	assert.True(t, hasErrorCode(err, ErrorCodeReadOnly))
+	err = client.Remove("/docs/a.txt")
+	assert.True(t, hasErrorCode(err, ErrorCodeReadOnly))
+	_, err = client.Rename("/docs/a.txt", "b.txt")
+	assert.True(t, hasErrorCode(err, ErrorCodeReadOnly))
+	_, err = client.Move("/docs/a.txt", "/docs")
+	assert.True(t, hasErrorCode(err, ErrorCodeReadOnly))
+	_, err = client.UploadFileFromStream("b.txt", strings.NewReader("payload"), "/docs")
+	assert.True(t, hasErrorCode(err, ErrorCodeReadOnly))
+
+	req, err := http.NewRequest(http.MethodPost, "https://cloud.mail.ru"+DownloadTokenURL, nil)
+	require.NoError(t, err)
+	assert.NoError(t, client.checkReadOnly(req))
+}
+
+func TestReadOnlyModeSendsNoRequests(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	client, server := newFakeCloud(t, mux)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		mux.ServeHTTP(w, r)
+	})
+	WithReadOnly(true)(client)
+
+	source := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(source, []byte("payload"), 0o644))
+	folder := &Folder{CloudStructureEntryBase: CloudStructureEntryBase{FullPath: "/docs"}}
+
+	calls := map[string]func() error{
+		"CreateFolder": func() error { _, err := client.CreateFolder("/docs/new"); return err },
+		"EnsureFolder": func() error { _, err := client.EnsureFolder("/docs/new"); return err },
+		"Remove":       func() error { return client.Remove("/docs/a.txt") },
+		"Rename":       func() error { _, err := client.Rename("/docs/a.txt", "b.txt"); return err },
+		"Move":         func() error { _, err := client.Move("/docs/a.txt", "/other"); return err },
+		"Copy":         func() error { _, err := client.Copy("/docs/a.txt", "/other"); return err },
+		"MoveRename":   func() error { _, err := client.MoveRename("/docs/a.txt", "/other", "b.txt"); return err },
+		"Publish":      func() error { _, err := client.Publish("/docs/a.txt"); return err },
+		"Unpublish":    func() error { _, err := client.Unpublish("https://cloud.mail.ru/public/abc"); return err },
+		"RestoreFileFromHistory": func() error {
+			_, err := client.RestoreFileFromHistory("/docs/a.txt", 1, true, "")
+			return err
+		},
+		"CreateFileFromHash": func() error {
+			_, err := client.CreateFileFromHash("/docs/b.txt", strings.Repeat("A", 40), 100, ConflictModeRename)
+			return err
+		},
+		"CopyByHash":   func() error { _, err := client.CopyByHash("/docs/a.txt", "/docs/b.txt"); return err },
+		"UploadFile":   func() error { _, err := client.UploadFile("a.txt", source, "/docs"); return err },
+		"UploadFileTo": func() error { _, err := client.UploadFileTo(folder, source); return err },
+		"UploadIfNewer": func() error {
+			_, _, err := client.UploadIfNewer("a.txt", source, "/docs")
+			return err
+		},
+		"UploadFileFromStream": func() error {
+			_, err := client.UploadFileFromStream("b.txt", strings.NewReader("payload"), "/docs")
+			return err
+		},
+		"UploadFileFromStreamTo": func() error {
+			_, err := client.UploadFileFromStreamTo(folder, "b.txt", strings.NewReader("payload"))
+			return err
+		},
+		"AcceptInvite":     func() error { _, err := client.AcceptInvite("invite", "shared"); return err },
+		"RejectInvite":     func() error { return client.RejectInvite("invite") },
+		"UploadManifest":   func() error { _, err := client.UploadManifest(t.TempDir(), "/docs"); return err },
+		"MergeFolders":     func() error { _, err := client.MergeFolders("/docs", []string{"/other"}); return err },
+		"RestoreFromTrash": func() error { return client.RestoreFromTrash(&TrashItem{FullPath: "/a.txt"}) },
+		"EmptyTrash":       func() error { return client.EmptyTrash() },
+	}
+	for name, call := range calls {
+		assert.True(t, hasErrorCode(call(), ErrorCodeReadOnly), name)
+	}
+	assert.Zero(t, atomic.LoadInt32(&requests))
+}
+
+func TestMoveToTrashFailure(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(Remove, func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("home") {
+		case "/missing.txt":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	err := client.MoveToTrash("/missing.txt")
+	assert.True(t, hasErrorCode(err, ErrorCodePathNotExists))
+
+	err = client.Remove("/locked.txt")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+func TestListSharedWithMe(t *testing.T) {
+	failing := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder/shared/incoming", func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = io.WriteString(w, `{"body":{"list":[`+
+			`{"type":"folder","name":"reports","home":"/reports","access":"read_only","owner":{"email":"boss@mail.ru","name":"Boss"}},`+
+			`{"type":"folder","name":"team","home":"/team","access":"read_write","owner":{"email":"lead@mail.ru"}}]}}`)
+	})
+	mux.HandleFunc(Remove, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/team", r.FormValue("home"))
+		_, _ = io.WriteString(w, `{"body":"/team"}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	folders, err := client.ListSharedWithMe()
+	require.NoError(t, err)
+	require.Len(t, folders, 2)
+	assert.Equal(t, "/reports", folders[0].FullPath)
+	assert.True(t, folders[0].ReadOnly)
+	assert.Equal(t, &Owner{Email: "boss@mail.ru", Name: "Boss"}, folders[0].Owner)
+	assert.False(t, folders[1].ReadOnly)
+
+	assert.True(t, hasErrorCode(folders[0].Remove(), ErrorCodeAccessDenied))
+	require.NoError(t, folders[1].Remove())
+
+	failing = true
+	_, err = client.ListSharedWithMe()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "500")
+}
+
+func TestEmptyShardListRetriesDispatcher(t *testing.T) {
+	dispatcherCalls := 0
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/api/v2/user/space", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"bytes_total":8192,"bytes_used":1024}`))
+	})
+	mux.HandleFunc("/api/v2/dispatcher", func(w http.ResponseWriter, r *http.Request) {
+		dispatcherCalls++
+		if dispatcherCalls == 2 {
+			_, _ = fmt.Fprintf(w, `{"body":{"get":[{"url":"%s/get/"}],"upload":[]}}`, server.URL)
+			return
+		}
+		_, _ = io.WriteString(w, `{"body":{"get":[],"upload":[]}}`)
+	})
+	account := NewAccount("test@mail.ru", "password", WithEndpoints(server.URL, server.URL))
+	account.authToken = "token"
+	account.initHttpClient(server.URL)
+	client := &CloudClient{Account: account, cancelCtx: context.Background()}
+
+	fileURL, err := client.getShardFileURL("file.txt", nil)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/get/file.txt", fileURL)
+	assert.Equal(t, 2, dispatcherCalls)
+
+	_, err = client.getUploadShardURL()
+	assert.True(t, hasErrorCode(err, ErrorCodeNoShardAvailable))
+	assert.Equal(t, 4, dispatcherCalls)
+	assert.Equal(t, int64(2), client.Stats().Retries)
+}
+
+func TestMoveWithOptionsCreateDest(t *testing.T) {
+	var created, quotaExceeded bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		switch home := r.URL.Query().Get("home"); {
+		case home == "/docs/":
+			_, _ = io.WriteString(w, `{"body":{"type":"folder","home":"/docs","list":[{"type":"file","name":"a.txt","home":"/docs/a.txt"}]}}`)
+		case home == "/archive/2024/" && created:
+			_, _ = io.WriteString(w, `{"body":{"type":"folder","home":"/archive/2024","list":[]}}`)
+		case home == "/unavailable/":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	mux.HandleFunc("/api/v2/folder/add", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "/archive/2024/", r.PostForm.Get("home"))
+		if quotaExceeded {
+			w.WriteHeader(http.StatusInsufficientStorage)
+			return
+		}
+		created = true
+		_, _ = io.WriteString(w, `{"body":"/archive/2024"}`)
+	})
+	mux.HandleFunc("/api/v2/file/move", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "/archive/2024", r.PostForm.Get("folder"))
+		_, _ = io.WriteString(w, `{"body":"/archive/2024/a.txt"}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	_, err := client.Move("/docs/a.txt", "/archive/2024")
+	assert.True(t, hasErrorCode(err, ErrorCodePathNotExists))
+	assert.False(t, created)
+
+	// Ошибка, отличная от отсутствия папки, возвращается без попытки создания
+	_, err = client.MoveWithOptions("/docs/a.txt", "/unavailable", MoveOptions{CreateDest: true})
+	require.Error(t, err)
+	assert.False(t, hasErrorCode(err, ErrorCodePathNotExists))
+	assert.False(t, created)
+
+	quotaExceeded = true
+	_, err = client.MoveWithOptions("/docs/a.txt", "/archive/2024", MoveOptions{CreateDest: true})
+	assert.True(t, hasErrorCode(err, ErrorCodeInsufficientStorage))
+	assert.ErrorContains(t, err, "создание папки назначения /archive/2024 не удалось")
+
+	quotaExceeded = false
+	moved, err := client.MoveWithOptions("/docs/a.txt", "/archive/2024", MoveOptions{CreateDest: true})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "/archive/2024/a.txt", moved.FullPath)
+}
+
+func TestDownloadHistoryRevisionWithoutHash(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file/history", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":[{"uid":2,"rev":2,"hash":"REVISION2HASH","size":10,"time":1700000000},{"uid":1,"rev":1,"size":5,"time":1690000000}]}`)
+	})
+	mux.HandleFunc("/get/a.txt", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "REVISION2HASH", r.URL.Query().Get("hash"))
+		_, _ = io.WriteString(w, "revision 2")
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	_, _, err := client.DownloadHistoryRevision("/a.txt", 1)
+	assert.True(t, hasErrorCode(err, ErrorCodeHistoryNotExists))
+
+	stream, _, err := client.DownloadHistoryRevision("/a.txt", 2)
+	require.NoError(t, err)
+	data, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+	assert.Equal(t, "revision 2", string(data))
+}
+
+func TestRestoreFileFromHistorySafely(t *testing.T) {
+	history := `{"body":[{"uid":1,"rev":1,"hash":"REVISION1HASH","size":5,"time":1690000000},` +
+		`{"uid":3,"rev":3,"hash":"REVISION3HASH","size":30,"time":1710000000},` +
+		`{"uid":2,"rev":2,"size":10,"time":1700000000}]}`
+	var created []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file/history", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, history)
+	})
+	mux.HandleFunc("/api/v2/file/add", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		created = append(created, r.PostForm.Get("home")+" "+r.PostForm.Get("hash"))
+		_, _ = fmt.Fprintf(w, `{"body":%q}`, r.PostForm.Get("home"))
+	})
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	client, _ := newFakeCloud(t, mux)
+	client.Account.ActivatedTariffs = []*Rate{{ID: "PAID"}}
+
+	// Текущей считается ревизия с наибольшим номером, а не первая запись ответа
+	result, err := client.RestoreFileFromHistorySafely("/docs/a.txt", 1, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), result.PreviousRevision)
+	assert.Equal(t, "/docs/a (rev 3).txt", result.BackupPath)
+	assert.Equal(t, []string{"/docs/a (rev 3).txt REVISION3HASH", "/docs/a.txt REVISION1HASH"}, created)
+
+	created = nil
+	_, err = client.RestoreFileFromHistorySafely("/docs/a.txt", 2, false)
+	assert.True(t, hasErrorCode(err, ErrorCodeHistoryNotExists))
+	assert.Empty(t, created)
+
+	// Без хеша текущей ревизии снимок не создается
+	history = `{"body":[{"uid":1,"rev":1,"hash":"REVISION1HASH","size":5,"time":1690000000},{"uid":2,"rev":2,"size":10,"time":1700000000}]}`
+	_, err = client.RestoreFileFromHistorySafely("/docs/a.txt", 1, true)
+	assert.True(t, hasErrorCode(err, ErrorCodeHistoryNotExists))
+	assert.Empty(t, created)
+}
+
+func TestStatViaShard(t *testing.T) {
+	var metadataRequests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		switch r.URL.Path {
+		case "/get/big.bin":
+			w.Header().Set("Content-Length", "1234")
+		case "/get/missing.bin":
+			w.WriteHeader(http.StatusNotFound)
+		case "/get/nolength.bin":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		home := r.URL.Query().Get("home")
+		metadataRequests = append(metadataRequests, home)
+		switch home {
+		case "/docs":
+			_, _ = io.WriteString(w, `{"body":{"type":"folder","name":"docs","home":"/docs"}}`)
+		default:
+			_, _ = fmt.Fprintf(w, `{"body":{"type":"file","name":"f","home":%q,"size":77}}`, home)
+		}
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	size, err := client.StatViaShard("/big.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), size)
+	assert.Empty(t, metadataRequests)
+
+	_, err = client.StatViaShard("/missing.bin")
+	assert.True(t, hasErrorCode(err, ErrorCodePathNotExists))
+	assert.Empty(t, metadataRequests)
+
+	// Шард не поддерживает HEAD или не вернул размер: размер берется из метаданных
+	size, err = client.StatViaShard("/nohead.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(77), size)
+	size, err = client.StatViaShard("/nolength.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(77), size)
+	assert.Equal(t, []string{"/nohead.bin", "/nolength.bin"}, metadataRequests)
+
+	_, err = client.StatViaShard("/docs")
+	assert.True(t, hasErrorCode(err, ErrorCodePathNotExists))
+	assert.ErrorContains(t, err, "папку")
+}
+
+func TestCopyByHash(t *testing.T) {
+	t.Run("создание по хешу", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, `{"body":{"type":"file","name":"src.txt","home":"/src.txt","hash":"ABCDEF","size":42}}`)
+		})
+		mux.HandleFunc("/api/v2/file/add", func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "/dst/copy.txt", r.PostForm.Get("home"))
+			assert.Equal(t, "ABCDEF", r.PostForm.Get("hash"))
+			assert.Equal(t, "42", r.PostForm.Get("size"))
+			_, _ = io.WriteString(w, `{"body":"/dst/copy.txt"}`)
+		})
+		mux.HandleFunc("/api/v2/file/copy", func(w http.ResponseWriter, r *http.Request) {
+			t.Error("копирование не должно выполняться при наличии хеша")
+		})
+		client, _ := newFakeCloud(t, mux)
+
+		file, err := client.CopyByHash("/src.txt", "/dst/copy.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "/dst/copy.txt", file.FullPath)
+		assert.Equal(t, "ABCDEF", file.Hash)
+		assert.Equal(t, int64(42), file.Size.DefaultValue)
+	})
+
+	t.Run("копирование без хеша", func(t *testing.T) {
+		var copied, renamed bool
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, `{"body":{"type":"file","name":"src.txt","home":"/src.txt","size":0}}`)
+		})
+		mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+			switch strings.TrimSuffix(r.URL.Query().Get("home"), "/") {
+			case "":
+				_, _ = io.WriteString(w, `{"body":{"type":"folder","home":"/","list":[{"type":"file","name":"src.txt","home":"/src.txt","size":0},{"type":"folder","name":"dst","home":"/dst"}]}}`)
+			case "/dst":
+				_, _ = io.WriteString(w, `{"body":{"type":"folder","name":"dst","home":"/dst","list":[{"type":"file","name":"src.txt","home":"/dst/src.txt","size":0}]}}`)
+			default:
+				http.NotFound(w, r)
+			}
+		})
+		mux.HandleFunc("/api/v2/file/add", func(w http.ResponseWriter, r *http.Request) {
+			t.Error("создание по хешу не должно выполняться без хеша")
+		})
+		mux.HandleFunc("/api/v2/file/copy", func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "/src.txt", r.PostForm.Get("home"))
+			assert.Equal(t, "/dst/", r.PostForm.Get("folder"))
+			copied = true
+			_, _ = io.WriteString(w, `{"body":"/dst/src.txt"}`)
+		})
+		mux.HandleFunc("/api/v2/file/rename", func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "/dst/src.txt", r.PostForm.Get("home"))
+			assert.Equal(t, "copy.txt", r.PostForm.Get("name"))
+			renamed = true
+			_, _ = io.WriteString(w, `{"body":"/dst/copy.txt"}`)
+		})
+		client, _ := newFakeCloud(t, mux)
+
+		file, err := client.CopyByHash("/src.txt", "/dst/copy.txt")
+		require.NoError(t, err)
+		assert.True(t, copied)
+		assert.True(t, renamed)
+		assert.Equal(t, "/dst/copy.txt", file.FullPath)
+		assert.Equal(t, "copy.txt", file.Name)
+	})
 }
@@ -5,6 +5,8 @@ const (
 	BaseMailRuCloud = "https://cloud.mail.ru"
 	// BaseMailRuAuth базовый адрес авторизации Mail.ru
 	BaseMailRuAuth = "https://auth.mail.ru"
+	// BaseMailRuBizCloud базовый адрес облака Mail.ru для бизнеса (корпоративные аккаунты на собственном домене)
+	BaseMailRuBizCloud = "https://cloud.biz.mail.ru"
 	// Auth URL авторизации
 	Auth = "/cgi-bin/auth"
 	// EnsureSdc адрес для обеспечения SDC cookies
@@ -15,6 +17,10 @@ const (
 	DiskSpace = "/api/v2/user/space?api=2&email=%s&token=%s"
 	// ItemsList список элементов облака
 	ItemsList = "/api/v2/folder?token=%s&home=%s"
+	// FileInfoURL информация об элементе облака по пути
+	FileInfoURL = "/api/v2/file?token=%s&home=%s"
+	// SharedIncomingURL список папок, к которым предоставили доступ текущему пользователю
+	SharedIncomingURL = "/api/v2/folder/shared/incoming?token=%s"
 	// PublicLink начало публичной ссылки
 	PublicLink = "https://cloud.mail.ru/public/"
 	// Dispatcher информация о шардах
@@ -31,6 +37,20 @@ const (
 	Rename = "/api/v2/file/rename"
 	// Remove удаление файла или папки
 	Remove = "/api/v2/file/remove"
+	// TrashURL список элементов корзины
+	TrashURL = "/api/v2/trashbin?token=%s"
+	// TrashRestore восстановление элемента из корзины
+	TrashRestore = "/api/v2/trashbin/restore"
+	// TrashEmpty очистка корзины
+	TrashEmpty = "/api/v2/trashbin/empty"
+	// RecentURL список недавно добавленных и измененных файлов
+	RecentURL = "/api/v2/folder/recent?token=%s&limit=%d"
+	// InvitesURL список входящих приглашений в общие папки
+	InvitesURL = "/api/v2/folder/invites?token=%s"
+	// InviteMount принятие приглашения с подключением общей папки
+	InviteMount = "/api/v2/folder/mount"
+	// InviteReject отклонение приглашения
+	InviteReject = "/api/v2/folder/invites/reject"
 	// HistoryURL URL истории файла
 	HistoryURL = "/api/v2/file/history?home=%s&api=2&email=%s&x-email=%s&token=%s"
 	// RatesURL URL тарифов
@@ -0,0 +1,53 @@
+package mailrucloud
+
+import "context"
+
+// FolderCursorPageSize количество элементов, запрашиваемых FolderCursor за один запрос
+const FolderCursorPageSize = 500
+
+// FolderCursor постранично получает элементы папки, запоминая смещение между вызовами Next.
+// Страницы запрашиваются по смещению, поэтому если содержимое папки меняется во время обхода,
+// элементы могут быть пропущены или получены повторно. Курсор не предназначен для одновременного
+// использования из нескольких горутин
+type FolderCursor struct {
+	client   *CloudClient
+	path     string
+	offset   int
+	finished bool
+}
+
+// GetFolderCursor создает курсор для постраничного получения элементов папки path по
+// FolderCursorPageSize элементов. Запросы выполняются только при вызове Next; если папка
+// не существует, Next возвращает ошибку с кодом ErrorCodePathNotExists
+func (c *CloudClient) GetFolderCursor(path string) (*FolderCursor, error) {
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+	return &FolderCursor{client: c, path: c.getPathStartEndSlash(path, true, false)}, nil
+}
+
+// Next возвращает следующую страницу элементов папки и признак того, что за ней могут следовать
+// другие страницы. После последней страницы Next возвращает nil и false. При ошибке смещение
+// не меняется, поэтому вызов можно повторить
+func (f *FolderCursor) Next(ctx context.Context) ([]*CloudStructureEntry, bool, error) {
+	if f.finished {
+		return nil, false, nil
+	}
+
+	entry, err := f.client.getFolderEntry(ctx, f.path, f.offset, FolderCursorPageSize)
+	if err != nil {
+		return nil, true, err
+	}
+
+	items := entry.List
+	if items == nil {
+		items = []*CloudStructureEntry{}
+	}
+	f.offset += len(items)
+
+	f.finished = len(items) < FolderCursorPageSize
+	if entry.Count != nil && f.offset >= entry.Count.Folders+entry.Count.Files {
+		f.finished = true
+	}
+	return items, !f.finished, nil
+}
@@ -0,0 +1,73 @@
+package mailrucloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFolderCursor(t *testing.T) {
+	const total = FolderCursorPageSize*2 + 3
+	var offsets []int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "/big folder/", query.Get("home"))
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		assert.Equal(t, FolderCursorPageSize, limit)
+		offsets = append(offsets, offset)
+
+		var items []string
+		for i := offset; i < total && i < offset+limit; i++ {
+			items = append(items, fmt.Sprintf(`{"type":"file","name":"%[1]d.txt","home":"/big folder/%[1]d.txt"}`, i))
+		}
+		_, _ = fmt.Fprintf(w, `{"body":{"type":"folder","home":"/big folder","count":{"folders":0,"files":%d},"list":[%s]}}`, total, strings.Join(items, ","))
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	cursor, err := client.GetFolderCursor("/big folder")
+	require.NoError(t, err)
+
+	var names []string
+	for {
+		items, more, err := cursor.Next(context.Background())
+		require.NoError(t, err)
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		if !more {
+			break
+		}
+	}
+	assert.Len(t, names, total)
+	assert.Equal(t, "0.txt", names[0])
+	assert.Equal(t, fmt.Sprintf("%d.txt", total-1), names[total-1])
+	assert.Equal(t, []int{0, FolderCursorPageSize, FolderCursorPageSize * 2}, offsets)
+
+	items, more, err := cursor.Next(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, items)
+	assert.False(t, more)
+}
+
+func TestFolderCursorMissingFolder(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"status":404}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	cursor, err := client.GetFolderCursor("/missing")
+	require.NoError(t, err)
+	_, _, err = cursor.Next(context.Background())
+	assert.True(t, hasErrorCode(err, ErrorCodePathNotExists))
+}
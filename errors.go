@@ -1,5 +1,10 @@
 package mailrucloud
 
+import (
+	"errors"
+	"fmt"
+)
+
 // ErrorCode определяет коды ошибок клиента облака
 type ErrorCode int
 
@@ -20,6 +25,30 @@ const (
 	ErrorCodeNotSupportedOperation
 	// ErrorCodePublicLinkNotExists - публичная ссылка не существует
 	ErrorCodePublicLinkNotExists
+	// ErrorCodeAccessDenied - недостаточно прав для операции
+	ErrorCodeAccessDenied
+	// ErrorCodeUploadFailed - шард загрузки отклонил содержимое файла
+	ErrorCodeUploadFailed
+	// ErrorCodeHashNotExists - содержимое с указанным хешем отсутствует в облаке
+	ErrorCodeHashNotExists
+	// ErrorCodeInsufficientStorage - недостаточно места в облаке
+	ErrorCodeInsufficientStorage
+	// ErrorCodeClientShutdown - клиент остановлен и не принимает новые операции
+	ErrorCodeClientShutdown
+	// ErrorCodeInviteExpired - приглашение в общую папку истекло или отозвано
+	ErrorCodeInviteExpired
+	// ErrorCodeInvalidName - имя файла или папки не соответствует правилам облака
+	ErrorCodeInvalidName
+	// ErrorCodeNotModified - файл не изменялся с указанного времени (304 Not Modified)
+	ErrorCodeNotModified
+	// ErrorCodeReadOnly - изменяющая операция запрещена режимом только для чтения
+	ErrorCodeReadOnly
+	// ErrorCodeCertificatePinMismatch - сертификат сервера не соответствует закрепленным ключам
+	ErrorCodeCertificatePinMismatch
+	// ErrorCodeNoShardAvailable - диспетчер не вернул шард нужного типа; операцию можно повторить позже
+	ErrorCodeNoShardAvailable
+	// ErrorCodeAlreadyExists - элемент с таким именем уже существует, а режим конфликта запрещает замену
+	ErrorCodeAlreadyExists
 )
 
 // CloudClientError представляет ошибку клиента облака
@@ -48,3 +77,51 @@ func (e *NotAuthorizedError) Error() string {
 	}
 	return e.Message
 }
+
+// responseDecodeSnippetSize количество первых байт тела ответа, включаемых в ResponseDecodeError
+const responseDecodeSnippetSize = 256
+
+// ResponseDecodeError представляет ошибку разбора ответа API, формат которого не соответствует ожидаемому
+type ResponseDecodeError struct {
+	// Snippet начало тела ответа
+	Snippet string
+	// Err исходная ошибка разбора
+	Err error
+}
+
+// newResponseDecodeError создает ошибку разбора ответа с началом тела data
+func newResponseDecodeError(data []byte, err error) *ResponseDecodeError {
+	if len(data) > responseDecodeSnippetSize {
+		data = data[:responseDecodeSnippetSize]
+	}
+	return &ResponseDecodeError{Snippet: string(data), Err: err}
+}
+
+func (e *ResponseDecodeError) Error() string {
+	return fmt.Sprintf("неожиданный формат ответа API: %v; начало ответа: %q", e.Err, e.Snippet)
+}
+
+// Unwrap возвращает исходную ошибку разбора
+func (e *ResponseDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// UnsafeArchiveEntryError представляет отклоненный при распаковке элемент архива, путь которого
+// после нормализации выходит за пределы папки распаковки (zip-slip): абсолютный путь, переход
+// на уровень выше через ".." или зарезервированное имя
+type UnsafeArchiveEntryError struct {
+	// Entry имя элемента в архиве
+	Entry string
+	// DestDir папка распаковки
+	DestDir string
+}
+
+func (e *UnsafeArchiveEntryError) Error() string {
+	return fmt.Sprintf("элемент архива %q выходит за пределы папки распаковки %s", e.Entry, e.DestDir)
+}
+
+// hasErrorCode проверяет, что ошибка является CloudClientError с указанным кодом
+func hasErrorCode(err error, code ErrorCode) bool {
+	var cloudErr *CloudClientError
+	return errors.As(err, &cloudErr) && cloudErr.ErrorCode == code
+}
@@ -0,0 +1,125 @@
+package mailrucloud
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DownloadAndExtract скачивает выбранные элементы текущей папки как ZIP архив сервера и распаковывает
+// его в локальную папку destDir без сохранения промежуточного файла архива
+func (f *Folder) DownloadAndExtract(fileAndFolderNames []string, destDir string) error {
+	return f.DownloadAndExtractContext(context.Background(), fileAndFolderNames, destDir)
+}
+
+// DownloadAndExtractContext скачивает и распаковывает ZIP архив выбранных элементов с учетом контекста.
+// Архив распаковывается через archive/zip, которому нужен центральный каталог в конце архива, поэтому
+// архив сначала скачивается в память целиком; файл архива на диск не записывается. Если путь хотя бы
+// одного элемента архива выходит за пределы destDir, возвращается ошибка UnsafeArchiveEntryError и
+// ничего не распаковывается. Отмена ctx прерывает скачивание и распаковку; при ошибке распаковки
+// уже распакованные файлы остаются в destDir
+func (f *Folder) DownloadAndExtractContext(ctx context.Context, fileAndFolderNames []string, destDir string) error {
+	paths := make([]string, len(fileAndFolderNames))
+	for i, name := range fileAndFolderNames {
+		paths[i] = f.FullPath + "/" + name
+	}
+
+	// Скачивание прерывается и при отмене ctx, и при остановке клиента через Shutdown
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(f.client.cancelCtx, cancel)
+	defer stop()
+
+	stream, _, err := f.client.downloadZIPArchive(ctx, paths)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	return extractZIPArchive(ctx, stream, destDir)
+}
+
+// extractZIPArchive читает ZIP архив из потока и распаковывает его в destDir. Пути всех элементов
+// проверяются до записи первого файла
+func extractZIPArchive(ctx context.Context, r io.Reader, destDir string) error {
+	data, err := io.ReadAll(&contextReader{ctx: ctx, reader: r})
+	if err != nil {
+		return err
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	destPaths := make([]string, len(archive.File))
+	for i, file := range archive.File {
+		if destPaths[i], err = zipEntryDestPath(destDir, file.Name); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return err
+	}
+	for i, file := range archive.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(destPaths[i], 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeZIPEntryFile(ctx, destPaths[i], file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipEntryDestPath возвращает путь распаковки элемента архива внутри destDir. Обратная косая черта
+// считается разделителем, как в архивах, созданных в Windows. Если путь элемента выходит за пределы
+// destDir, возвращается UnsafeArchiveEntryError
+func zipEntryDestPath(destDir, name string) (string, error) {
+	relative := filepath.FromSlash(strings.TrimSuffix(strings.ReplaceAll(name, `\`, "/"), "/"))
+	if !filepath.IsLocal(relative) {
+		return "", &UnsafeArchiveEntryError{Entry: name, DestDir: destDir}
+	}
+
+	destPath := filepath.Join(destDir, relative)
+	// Дополнительная проверка итогового пути после filepath.Join
+	if rel, err := filepath.Rel(destDir, destPath); err != nil || !filepath.IsLocal(rel) {
+		return "", &UnsafeArchiveEntryError{Entry: name, DestDir: destDir}
+	}
+	return destPath, nil
+}
+
+// writeZIPEntryFile записывает содержимое элемента архива в файл, создавая родительские папки.
+// Контрольную сумму CRC32 проверяет archive/zip при чтении содержимого
+func writeZIPEntryFile(ctx context.Context, destPath string, entry *zip.File) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
+		return err
+	}
+
+	content, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	file, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, &contextReader{ctx: ctx, reader: content}); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
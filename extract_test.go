@@ -0,0 +1,183 @@
+package mailrucloud
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractZIPArchive(t *testing.T) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+
+	deflated, err := writer.Create("Документы/отчет.txt")
+	require.NoError(t, err)
+	_, err = deflated.Write(bytes.Repeat([]byte("сжатое содержимое "), 100))
+	require.NoError(t, err)
+
+	_, err = writer.Create("Пустая/")
+	require.NoError(t, err)
+
+	// Несжатое содержимое содержит сигнатуру дескриптора данных
+	stored, err := writer.CreateHeader(&zip.FileHeader{Name: "stored.bin", Method: zip.Store})
+	require.NoError(t, err)
+	storedContent := []byte("PK\x07\x08несжатое содержимое")
+	_, err = stored.Write(storedContent)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	destDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, extractZIPArchive(context.Background(), bytes.NewReader(buf.Bytes()), destDir))
+
+	content, err := os.ReadFile(filepath.Join(destDir, "Документы", "отчет.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte("сжатое содержимое "), 100), content)
+
+	content, err = os.ReadFile(filepath.Join(destDir, "stored.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, storedContent, content)
+
+	info, err := os.Stat(filepath.Join(destDir, "Пустая"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestExtractZIPArchiveRejectsTraversal(t *testing.T) {
+	for _, name := range []string{"../../etc/passwd", "/etc/passwd", `..\..\etc\passwd`, "safe/../../passwd"} {
+		var buf bytes.Buffer
+		writer := zip.NewWriter(&buf)
+		entry, err := writer.Create(name)
+		require.NoError(t, err)
+		_, err = entry.Write([]byte("root:x:0:0"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		root := t.TempDir()
+		destDir := filepath.Join(root, "a", "b")
+		err = extractZIPArchive(context.Background(), bytes.NewReader(buf.Bytes()), destDir)
+
+		var unsafeErr *UnsafeArchiveEntryError
+		require.True(t, errors.As(err, &unsafeErr), name)
+		assert.Equal(t, name, unsafeErr.Entry)
+		assert.Equal(t, destDir, unsafeErr.DestDir)
+
+		_, err = os.Stat(filepath.Join(root, "etc", "passwd"))
+		assert.True(t, os.IsNotExist(err), name)
+		_, err = os.Stat(filepath.Join(root, "a", "passwd"))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+}
+
+func TestZIPEntryDestPath(t *testing.T) {
+	destDir := t.TempDir()
+	path, err := zipEntryDestPath(destDir, "Документы/../отчет.txt")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(destDir, "отчет.txt"), path)
+
+	_, err = zipEntryDestPath(destDir, "..")
+	assert.Error(t, err)
+}
+
+// newZIPArchiveCloud создает фейковое облако, отдающее по ссылке на архив содержимое archive
+func newZIPArchiveCloud(t *testing.T, archive http.HandlerFunc) (*CloudClient, *[]string) {
+	t.Helper()
+
+	var homeLists []string
+	mux := http.NewServeMux()
+	client, server := newFakeCloud(t, mux)
+	mux.HandleFunc(CreateZipArchive, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		homeLists = append(homeLists, r.PostForm.Get("home_list"))
+		_, _ = fmt.Fprintf(w, `{"body":%q}`, server.URL+"/zip/archive.zip")
+	})
+	mux.HandleFunc("/zip/archive.zip", archive)
+	return client, &homeLists
+}
+
+func TestDownloadAndExtract(t *testing.T) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	entry, err := writer.Create("docs/a.txt")
+	require.NoError(t, err)
+	_, err = entry.Write([]byte("content of a"))
+	require.NoError(t, err)
+	entry, err = writer.Create("docs/sub/b.txt")
+	require.NoError(t, err)
+	_, err = entry.Write([]byte("content of b"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	client, homeLists := newZIPArchiveCloud(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(buf.Bytes())
+	})
+	folder := &Folder{CloudStructureEntryBase: CloudStructureEntryBase{FullPath: "/root", client: client}}
+
+	destDir := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, folder.DownloadAndExtract([]string{"docs"}, destDir))
+	assert.Equal(t, []string{`["/root/docs"]`}, *homeLists)
+
+	content, err := os.ReadFile(filepath.Join(destDir, "docs", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "content of a", string(content))
+	content, err = os.ReadFile(filepath.Join(destDir, "docs", "sub", "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "content of b", string(content))
+}
+
+func TestDownloadAndExtractRejectsTraversalBeforeWriting(t *testing.T) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, name := range []string{"a.txt", "../evil.txt"} {
+		entry, err := writer.Create(name)
+		require.NoError(t, err)
+		_, err = entry.Write([]byte(name))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	client, _ := newZIPArchiveCloud(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(buf.Bytes())
+	})
+	folder := &Folder{CloudStructureEntryBase: CloudStructureEntryBase{FullPath: "/root", client: client}}
+
+	root := t.TempDir()
+	destDir := filepath.Join(root, "out")
+	err := folder.DownloadAndExtract([]string{"a.txt"}, destDir)
+	var unsafeErr *UnsafeArchiveEntryError
+	require.ErrorAs(t, err, &unsafeErr)
+	assert.Equal(t, "../evil.txt", unsafeErr.Entry)
+
+	_, err = os.Stat(filepath.Join(destDir, "a.txt"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(root, "evil.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDownloadAndExtractContextCancelsRequest(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	client, _ := newZIPArchiveCloud(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	folder := &Folder{CloudStructureEntryBase: CloudStructureEntryBase{FullPath: "/root", client: client}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := folder.DownloadAndExtractContext(ctx, []string{"a.txt"}, t.TempDir())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
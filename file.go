@@ -1,7 +1,16 @@
 package mailrucloud
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -12,6 +21,25 @@ type File struct {
 	Hash string
 	// LastModifiedTimeUTC время последней модификации файла в формате UTC
 	LastModifiedTimeUTC time.Time
+	// Revision ревизия содержимого файла (0, если сервер ее не вернул)
+	Revision int64
+}
+
+// newFileFromEntry создает объект File из записи структуры облака
+func newFileFromEntry(item *CloudStructureEntry, client *CloudClient) *File {
+	return &File{
+		CloudStructureEntryBase: CloudStructureEntryBase{
+			FullPath:   item.Home,
+			Name:       item.Name,
+			PublicLink: client.Account.buildPublicLink(item.Weblink),
+			Size:       NewSize(item.Size),
+			account:    client.Account,
+			client:     client,
+		},
+		Hash:                item.Hash,
+		LastModifiedTimeUTC: time.Unix(item.Mtime, 0).UTC(),
+		Revision:            int64(item.Rev),
+	}
 }
 
 // GetFileOneTimeDirectLink предоставляет одноразовую анонимную прямую ссылку для скачивания файла
@@ -29,6 +57,36 @@ func (f *File) Publish() (*File, error) {
 	return f, nil
 }
 
+// SetLinkDownloadLimit устанавливает максимальное количество скачиваний по публичной ссылке файла.
+// Если файл еще не опубликован, он публикуется
+func (f *File) SetLinkDownloadLimit(maxDownloads int) error {
+	result, err := f.client.PublishWithOptions(f.FullPath, PublishOptions{MaxDownloads: maxDownloads})
+	if err != nil {
+		return err
+	}
+	f.PublicLink = result.PublicLink
+	return nil
+}
+
+// GetLinkStats получает статистику публичной ссылки текущего файла
+func (f *File) GetLinkStats() (*LinkStats, error) {
+	return f.client.GetLinkStats(f.FullPath)
+}
+
+// PublicLinkQR возвращает изображение PNG с QR кодом публичной ссылки файла. Код формируется
+// локально без запросов к серверу. Если файл не опубликован, возвращается ошибка
+// с кодом ErrorCodePublicLinkNotExists
+func (f *File) PublicLinkQR() ([]byte, error) {
+	if f.PublicLink == "" {
+		return nil, &CloudClientError{
+			Message:   "Файл не опубликован",
+			Source:    f.FullPath,
+			ErrorCode: ErrorCodePublicLinkNotExists,
+		}
+	}
+	return encodeQRPNG([]byte(f.PublicLink))
+}
+
 // Unpublish отменяет публикацию текущего файла
 func (f *File) Unpublish() (*File, error) {
 	if f.PublicLink == "" {
@@ -42,6 +100,35 @@ func (f *File) Unpublish() (*File, error) {
 	return f, nil
 }
 
+// Update загружает новое содержимое текущего файла по тому же пути с перезаписью (conflict=rewrite).
+// Предыдущее содержимое сохраняется в истории файла как отдельная ревизия (GetFileHistory).
+// Поля текущего объекта обновляются новыми хешем, размером, временем модификации и ревизией
+func (f *File) Update(content io.Reader, opts ...UploadOption) (*File, error) {
+	result, err := f.client.uploadFromStream(f.Name, content, f.client.getParentCloudPath(f.FullPath),
+		func(existing *File) ConflictMode { return ConflictModeRewrite }, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := result.File
+	if updated.Revision == 0 {
+		// Сервер не вернул ревизию в метаданных файла: берется последняя ревизия из истории
+		if histories, err := f.client.GetFileHistory(updated.FullPath); err == nil {
+			for _, history := range histories {
+				updated.Revision = max(updated.Revision, history.Revision)
+			}
+		}
+	}
+
+	f.FullPath = updated.FullPath
+	f.Name = updated.Name
+	f.Size = updated.Size
+	f.Hash = updated.Hash
+	f.LastModifiedTimeUTC = updated.LastModifiedTimeUTC
+	f.Revision = updated.Revision
+	return f, nil
+}
+
 // RestoreFileFromHistory восстанавливает файл из истории
 func (f *File) RestoreFileFromHistory(historyRevision int64, rewriteExisting bool, newFileName string) (*File, error) {
 	return f.client.RestoreFileFromHistory(f.FullPath, historyRevision, rewriteExisting, newFileName)
@@ -52,7 +139,12 @@ func (f *File) GetFileHistory() ([]*History, error) {
 	return f.client.GetFileHistory(f.FullPath)
 }
 
-// Remove удаляет текущий файл из облака
+// DownloadHistoryRevision получает поток для скачивания указанной ревизии текущего файла из истории
+func (f *File) DownloadHistoryRevision(historyRevision int64) (io.ReadCloser, int64, error) {
+	return f.client.DownloadHistoryRevision(f.FullPath, historyRevision)
+}
+
+// Remove удаляет текущий файл из облака (файл перемещается в корзину)
 func (f *File) Remove() error {
 	return f.client.Remove(f.FullPath)
 }
@@ -94,10 +186,63 @@ func (f *File) Move(destFolderPath string) (*File, error) {
 	return f, nil
 }
 
-// DownloadFile скачивает текущий файл из облака
+// DownloadFile скачивает текущий файл из облака в локальную папку destFolderPath под именем
+// destFileName (если имя пустое, используется имя файла в облаке). Существующий файл перезаписывается
 func (f *File) DownloadFile(destFileName, destFolderPath string) error {
-	_, _, err := f.client.DownloadFile(f.FullPath)
-	return err
+	if destFileName == "" {
+		destFileName = f.Name
+	}
+	return f.downloadTo(filepath.Join(destFolderPath, destFileName), true)
+}
+
+// Save скачивает текущий файл в локальную папку destDir под его именем в облаке, создавая папку
+// при необходимости, и возвращает путь записанного файла. Если файл уже существует и overwrite
+// равен false, возвращается ошибка. Содержимое записывается во временный файл и проверяется
+// по хешу Mail.ru, поэтому при сбое скачивания существующий файл не повреждается
+func (f *File) Save(destDir string, overwrite bool) (string, error) {
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return "", err
+	}
+
+	destPath := filepath.Join(destDir, filepath.Base(f.Name))
+	if err := f.downloadTo(destPath, overwrite); err != nil {
+		return "", err
+	}
+	return destPath, nil
+}
+
+// downloadTo скачивает файл во временный файл рядом с destPath, сверяет хеш содержимого
+// с известным хешем файла и переименовывает временный файл в destPath
+func (f *File) downloadTo(destPath string, overwrite bool) error {
+	if !overwrite {
+		if _, err := os.Stat(destPath); err == nil {
+			return fmt.Errorf("файл %s уже существует", destPath)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(destPath), "."+filepath.Base(destPath)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	hasher := newMailRuHasher()
+	err = f.DownloadFileToStream(io.MultiWriter(tmpFile, hasher))
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if f.Hash != "" {
+		if hash := hasher.Sum(); !strings.EqualFold(hash, f.Hash) {
+			return fmt.Errorf("хеш скачанного файла %s не совпадает с хешем в облаке %s", hash, f.Hash)
+		}
+	}
+	return os.Rename(tmpFile.Name(), destPath)
 }
 
 // DownloadFileToStream скачивает текущий файл из облака в поток
@@ -107,7 +252,7 @@ func (f *File) DownloadFileToStream(destStream io.Writer) error {
 		return err
 	}
 	defer stream.Close()
-	_, err = io.Copy(destStream, stream)
+	_, err = f.client.copyBuffer(destStream, stream)
 	return err
 }
 
@@ -116,6 +261,89 @@ func (f *File) DownloadFileStream() (io.ReadCloser, int64, error) {
 	return f.client.DownloadFile(f.FullPath)
 }
 
+// ServeHTTP отдает содержимое файла по HTTP, реализуя http.Handler. Запросы с заголовком Range
+// передаются на шард Get как Range запросы, поэтому файл можно отдавать частями (например, для
+// перемотки медиа) без скачивания целиком. Заголовки Content-Type (по расширению имени),
+// Accept-Ranges, Content-Length и Last-Modified, а также условные запросы If-Modified-Since
+// обрабатываются через http.ServeContent на основе метаданных файла
+func (f *File) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fileURL, err := f.client.getShardFileURL(strings.TrimPrefix(f.FullPath, "/"), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	var content *rangeReader
+	if f.Size != nil && f.Size.DefaultValue >= 0 {
+		// Размер известен из метаданных, поэтому запрос к шарду выполняется только при чтении
+		content = &rangeReader{client: f.client, ctx: r.Context(), url: fileURL, size: f.Size.DefaultValue}
+	} else if content, err = newRangeReader(r.Context(), f.client, fileURL); err != nil {
+		status := http.StatusBadGateway
+		if hasErrorCode(err, ErrorCodePathNotExists) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer content.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(f.Name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if f.Hash != "" {
+		w.Header().Set("ETag", `"`+f.Hash+`"`)
+	}
+
+	http.ServeContent(w, r, f.Name, f.LastModifiedTimeUTC, content)
+}
+
+const (
+	// virusScanPollInitialInterval интервал перед первым повторным запросом статуса проверки
+	virusScanPollInitialInterval = 500 * time.Millisecond
+	// virusScanPollMaxInterval максимальный интервал между запросами статуса проверки
+	virusScanPollMaxInterval = 10 * time.Second
+)
+
+// WaitForVirusScan ожидает завершения антивирусной проверки файла, запрашивая его метаданные
+// с удваивающимся интервалом (от 0.5 до 10 секунд), пока статус не станет завершенным
+// (VirusScanPass или VirusScanFail) или не истечет ctx. Полезно перед публикацией только что
+// загруженного файла: до окончания проверки публичная ссылка может быть заблокирована.
+// При истечении ctx возвращается последний полученный статус и ошибка ctx. Если сервер не сообщает
+// статус проверки (файл не проверяется) или статус неизвестен, сразу возвращается ошибка с кодом
+// ErrorCodeNotSupportedOperation
+func (f *File) WaitForVirusScan(ctx context.Context) (VirusScanStatus, error) {
+	interval := virusScanPollInitialInterval
+	for {
+		entry, err := f.client.getEntryContext(ctx, f.FullPath)
+		if err != nil {
+			return "", err
+		}
+
+		status := VirusScanStatus(entry.VirusScan)
+		if status.IsTerminal() {
+			return status, nil
+		}
+		if status != VirusScanNotChecked {
+			return status, &CloudClientError{
+				Message:   fmt.Sprintf("Статус антивирусной проверки не поддерживается: %q", status),
+				Source:    f.FullPath,
+				ErrorCode: ErrorCodeNotSupportedOperation,
+			}
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return status, ctx.Err()
+		case <-timer.C:
+		}
+		interval = min(interval*2, virusScanPollMaxInterval)
+	}
+}
+
 // AbortAllAsyncTasks прерывает выполняющиеся асинхронные задачи
 func (f *File) AbortAllAsyncTasks() {
 	f.client.AbortAllAsyncTasks()
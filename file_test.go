@@ -0,0 +1,205 @@
+package mailrucloud
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileServeHTTP(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 10))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get/video.mp4", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	file := &File{
+		CloudStructureEntryBase: CloudStructureEntryBase{
+			Name:     "video.mp4",
+			FullPath: "/video.mp4",
+			Size:     NewSize(int64(len(content))),
+			account:  client.Account,
+			client:   client,
+		},
+		LastModifiedTimeUTC: modified,
+	}
+
+	req := httptest.NewRequest("GET", "/video.mp4", nil)
+	req.Header.Set("Range", "bytes=10-19")
+	rec := httptest.NewRecorder()
+	file.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "video/mp4", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+	assert.Equal(t, "10", rec.Header().Get("Content-Length"))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Equal(t, content[10:20], body)
+
+	req = httptest.NewRequest("GET", "/video.mp4", nil)
+	req.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	file.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestFileSave(t *testing.T) {
+	content := "file content for save test"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get/docs/report.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, content)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	hash, _, err := ComputeFileHash(strings.NewReader(content))
+	require.NoError(t, err)
+	file := &File{
+		CloudStructureEntryBase: CloudStructureEntryBase{
+			Name:     "report.txt",
+			FullPath: "/docs/report.txt",
+			account:  client.Account,
+			client:   client,
+		},
+		Hash: hash,
+	}
+
+	destDir := filepath.Join(t.TempDir(), "nested")
+	path, err := file.Save(destDir, false)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(destDir, "report.txt"), path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	_, err = file.Save(destDir, false)
+	assert.Error(t, err)
+	_, err = file.Save(destDir, true)
+	assert.NoError(t, err)
+
+	file.Hash = strings.Repeat("0", 40)
+	require.Error(t, file.DownloadFile("other.txt", destDir))
+	_, err = os.Stat(filepath.Join(destDir, "other.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileUpdate(t *testing.T) {
+	content := "new revision of the document"
+	hash, _, err := ComputeFileHash(strings.NewReader(content))
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","home":"/docs","list":[{"type":"file","name":"doc.txt","home":"/docs/doc.txt","hash":"OLDHASH","rev":1}]}}`)
+	})
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, `"`+hash+`"`)
+	})
+	mux.HandleFunc("/api/v2/file/add", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "rewrite", r.PostForm.Get("conflict"))
+		assert.Equal(t, "/docs/doc.txt", r.PostForm.Get("home"))
+		_, _ = io.WriteString(w, `{"body":"/docs/doc.txt"}`)
+	})
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"body":{"type":"file","name":"doc.txt","home":"/docs/doc.txt","hash":"%s","size":%d,"rev":2}}`, hash, len(content))
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	file := &File{
+		CloudStructureEntryBase: CloudStructureEntryBase{
+			Name:     "doc.txt",
+			FullPath: "/docs/doc.txt",
+			account:  client.Account,
+			client:   client,
+		},
+		Hash:     "OLDHASH",
+		Revision: 1,
+	}
+	updated, err := file.Update(strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, "/docs/doc.txt", updated.FullPath)
+	assert.Equal(t, hash, updated.Hash)
+	assert.Equal(t, int64(2), updated.Revision)
+	assert.Equal(t, int64(len(content)), updated.Size.DefaultValue)
+}
+
+func TestFileWaitForVirusScan(t *testing.T) {
+	var polls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		status := "not_checked"
+		if polls > 1 {
+			status = "pass"
+		}
+		_, _ = fmt.Fprintf(w, `{"body":{"type":"file","name":"a.txt","home":"/a.txt","virus_scan":"%s"}}`, status)
+	})
+	client, _ := newFakeCloud(t, mux)
+	file := &File{CloudStructureEntryBase: CloudStructureEntryBase{Name: "a.txt", FullPath: "/a.txt", client: client}}
+
+	status, err := file.WaitForVirusScan(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, VirusScanPass, status)
+	assert.Equal(t, 2, polls)
+
+	polls = -10
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	status, err = file.WaitForVirusScan(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, VirusScanNotChecked, status)
+}
+
+func TestFileWaitForVirusScanWithoutStatus(t *testing.T) {
+	var polls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		_, _ = io.WriteString(w, `{"body":{"type":"file","name":"a.txt","home":"/a.txt"}}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+	file := &File{CloudStructureEntryBase: CloudStructureEntryBase{Name: "a.txt", FullPath: "/a.txt", client: client}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	status, err := file.WaitForVirusScan(ctx)
+	assert.True(t, hasErrorCode(err, ErrorCodeNotSupportedOperation))
+	assert.Empty(t, status)
+	assert.Equal(t, 1, polls)
+}
+
+func TestFileWaitForVirusScanCancelsRequest(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	client, _ := newFakeCloud(t, mux)
+	file := &File{CloudStructureEntryBase: CloudStructureEntryBase{Name: "a.txt", FullPath: "/a.txt", client: client}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := file.WaitForVirusScan(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -1,10 +1,15 @@
 package mailrucloud
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,16 +20,60 @@ type Folder struct {
 	FoldersCount int
 	// FilesCount количество файлов в этой папке в облаке
 	FilesCount int
+	// TreeID идентификатор узла дерева облака. Не меняется при переименовании и перемещении
+	TreeID string
+	// CreatedTimeUTC время создания папки в UTC (нулевое, если сервер его не вернул)
+	CreatedTimeUTC time.Time
+	// LastModifiedTimeUTC время последней модификации папки в UTC (нулевое, если сервер его не вернул)
+	LastModifiedTimeUTC time.Time
+	// Owner владелец папки, если папка предоставлена в общий доступ другим пользователем
+	Owner *Owner
+	// ReadOnly указывает, что папка смонтирована из общего доступа только для чтения
+	ReadOnly bool
 	// Items список записей структуры облака
 	Items []*CloudStructureEntry
 	// prevDiskUsed предыдущее значение используемого облачного дискового пространства
 	prevDiskUsed int64
 	// lastItemsGettingTime время последнего получения элементов
 	lastItemsGettingTime time.Time
+	// removed указывает, что папка удалена через Remove и операции с ней недоступны
+	removed bool
 }
 
-// GetFiles получает список файлов в текущей папке
+// newFolderFromEntry создает объект Folder из записи структуры облака
+func newFolderFromEntry(item *CloudStructureEntry, client *CloudClient) *Folder {
+	var foldersCount, filesCount int
+	if item.Count != nil {
+		foldersCount = item.Count.Folders
+		filesCount = item.Count.Files
+	}
+
+	return &Folder{
+		CloudStructureEntryBase: CloudStructureEntryBase{
+			FullPath:     item.Home,
+			Name:         item.Name,
+			PublicLink:   client.Account.buildPublicLink(item.Weblink),
+			Size:         NewSize(item.Size),
+			FilesCount:   filesCount,
+			FoldersCount: foldersCount,
+			account:      client.Account,
+			client:       client,
+		},
+		FoldersCount:        foldersCount,
+		FilesCount:          filesCount,
+		TreeID:              item.Tree,
+		CreatedTimeUTC:      unixTimeUTC(item.Time),
+		LastModifiedTimeUTC: unixTimeUTC(item.Mtime),
+		Items:               item.List,
+	}
+}
+
+// GetFiles получает список файлов в текущей папке. Для удаленной папки возвращается пустой список;
+// чтобы получить ошибку, используйте ListFiles
 func (f *Folder) GetFiles() []*File {
+	if f.removed {
+		return []*File{}
+	}
 	f.updateFolderInfo(false)
 	if f.Items == nil {
 		return []*File{}
@@ -33,29 +82,18 @@ func (f *Folder) GetFiles() []*File {
 	var files []*File
 	for _, item := range f.Items {
 		if item.Type == "file" {
-			publicLink := ""
-			if item.Weblink != "" {
-				publicLink = PublicLink + item.Weblink
-			}
-			files = append(files, &File{
-				CloudStructureEntryBase: CloudStructureEntryBase{
-					FullPath:   item.Home,
-					Name:       item.Name,
-					PublicLink: publicLink,
-					Size:       NewSize(item.Size),
-					account:    f.account,
-					client:     f.client,
-				},
-				Hash:                item.Hash,
-				LastModifiedTimeUTC: time.Unix(item.Mtime, 0).UTC(),
-			})
+			files = append(files, newFileFromEntry(item, f.client))
 		}
 	}
 	return files
 }
 
-// GetFolders получает список подпапок в текущей папке
+// GetFolders получает список подпапок в текущей папке. Для удаленной папки возвращается пустой список;
+// чтобы получить ошибку, используйте ListFolders
 func (f *Folder) GetFolders() []*Folder {
+	if f.removed {
+		return []*Folder{}
+	}
 	f.updateFolderInfo(false)
 	if f.Items == nil {
 		return []*Folder{}
@@ -64,30 +102,39 @@ func (f *Folder) GetFolders() []*Folder {
 	var folders []*Folder
 	for _, item := range f.Items {
 		if item.Type == "folder" {
-			publicLink := ""
-			if item.Weblink != "" {
-				publicLink = PublicLink + item.Weblink
-			}
-			folders = append(folders, &Folder{
-				CloudStructureEntryBase: CloudStructureEntryBase{
-					FullPath:   item.Home,
-					Name:       item.Name,
-					PublicLink: publicLink,
-					Size:       NewSize(item.Size),
-					account:    f.account,
-					client:     f.client,
-				},
-				FoldersCount: item.Count.Folders,
-				FilesCount:   item.Count.Files,
-				Items:        item.List,
-			})
+			folder := newFolderFromEntry(item, f.client)
+			folder.Owner = f.Owner
+			folder.ReadOnly = f.ReadOnly
+			folders = append(folders, folder)
 		}
 	}
 	return folders
 }
 
+// ListFiles получает список файлов в текущей папке. Если папка удалена через Remove,
+// возвращается ошибка с кодом ErrorCodePathNotExists
+func (f *Folder) ListFiles() ([]*File, error) {
+	if err := f.checkRemoved(); err != nil {
+		return nil, err
+	}
+	return f.GetFiles(), nil
+}
+
+// ListFolders получает список подпапок в текущей папке. Если папка удалена через Remove,
+// возвращается ошибка с кодом ErrorCodePathNotExists
+func (f *Folder) ListFolders() ([]*Folder, error) {
+	if err := f.checkRemoved(); err != nil {
+		return nil, err
+	}
+	return f.GetFolders(), nil
+}
+
 // Publish публикует текущую папку
 func (f *Folder) Publish() (*Folder, error) {
+	if err := f.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	result, err := f.client.Publish(f.FullPath)
 	if err != nil {
 		return nil, err
@@ -96,8 +143,44 @@ func (f *Folder) Publish() (*Folder, error) {
 	return f, nil
 }
 
+// PublishAllFiles публикует каждый файл текущей папки (и вложенных папок, если recursive равен true)
+// и возвращает соответствие полного пути файла его публичной ссылке. При частичных ошибках
+// возвращаются успешно опубликованные файлы вместе с объединенной ошибкой
+func (f *Folder) PublishAllFiles(recursive bool) (map[string]string, error) {
+	if err := f.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	maxDepth := 0
+	if recursive {
+		maxDepth = -1
+	}
+	// Ошибки получения списков папок возвращаются вместе с ошибками публикации
+	files, err := f.client.collectFiles(context.Background(), f.FullPath, maxDepth, func(*File) bool { return true })
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	links := make(map[string]string)
+	for _, file := range files {
+		result, err := f.client.Publish(file.FullPath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file.FullPath, err))
+			continue
+		}
+		links[file.FullPath] = result.PublicLink
+	}
+
+	return links, errors.Join(errs...)
+}
+
 // Unpublish отменяет публикацию текущей папки
 func (f *Folder) Unpublish() (*Folder, error) {
+	if err := f.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	if f.PublicLink == "" {
 		return f, nil
 	}
@@ -109,18 +192,28 @@ func (f *Folder) Unpublish() (*Folder, error) {
 	return f, nil
 }
 
-// Remove удаляет текущую папку из облака
+// Remove удаляет текущую папку из облака (папка перемещается в корзину). После удаления объект
+// помечается удаленным: список элементов очищается, а последующие операции с ним возвращают ошибку
 func (f *Folder) Remove() error {
+	if err := f.checkWritable(); err != nil {
+		return err
+	}
+
 	err := f.client.Remove(f.FullPath)
 	if err != nil {
 		return err
 	}
-	f.updateFolderInfo(true)
+	f.removed = true
+	f.Items = []*CloudStructureEntry{}
 	return nil
 }
 
 // Rename переименовывает текущую папку
 func (f *Folder) Rename(newName string) (*Folder, error) {
+	if err := f.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	result, err := f.client.Rename(f.FullPath, newName)
 	if err != nil {
 		return nil, err
@@ -134,6 +227,10 @@ func (f *Folder) Rename(newName string) (*Folder, error) {
 
 // Copy копирует папку в другое пространство
 func (f *Folder) Copy(destFolderPath string) (*Folder, error) {
+	if err := f.checkRemoved(); err != nil {
+		return nil, err
+	}
+
 	result, err := f.client.Copy(f.FullPath, destFolderPath)
 	if err != nil {
 		return nil, err
@@ -147,6 +244,10 @@ func (f *Folder) Copy(destFolderPath string) (*Folder, error) {
 
 // Move перемещает папку в другое пространство
 func (f *Folder) Move(destFolderPath string) (*Folder, error) {
+	if err := f.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	result, err := f.client.Move(f.FullPath, destFolderPath)
 	if err != nil {
 		return nil, err
@@ -160,6 +261,10 @@ func (f *Folder) Move(destFolderPath string) (*Folder, error) {
 
 // CreateFolder создает новую папку в текущей папке
 func (f *Folder) CreateFolder(folderName string) (*Folder, error) {
+	if err := f.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	if strings.Contains(folderName, "/") {
 		return nil, &CloudClientError{
 			Message:   "Вложенные поддиректории не разрешены. Используйте CloudClient.CreateFolder вместо этого",
@@ -177,7 +282,11 @@ func (f *Folder) CreateFolder(folderName string) (*Folder, error) {
 
 // UploadFile загружает файл в облако
 func (f *Folder) UploadFile(sourceFilePath string) (*File, error) {
-	result, err := f.client.UploadFile("", sourceFilePath, f.FullPath)
+	if err := f.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	result, err := f.client.UploadFileTo(f, sourceFilePath)
 	if err != nil {
 		return nil, err
 	}
@@ -186,8 +295,12 @@ func (f *Folder) UploadFile(sourceFilePath string) (*File, error) {
 }
 
 // UploadFileFromStream загружает файл в облако из потока
-func (f *Folder) UploadFileFromStream(fileName string, content io.Reader) (*File, error) {
-	result, err := f.client.UploadFileFromStream(fileName, content, f.FullPath)
+func (f *Folder) UploadFileFromStream(fileName string, content io.Reader, opts ...UploadOption) (*File, error) {
+	if err := f.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	result, err := f.client.UploadFileFromStreamTo(f, fileName, content, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -228,13 +341,123 @@ func (f *Folder) DownloadFolderAsZIPStream() (io.ReadCloser, int64, error) {
 	return f.client.DownloadItemsAsZIPArchive([]string{f.FullPath})
 }
 
+// Tree строит дерево вложенных папок текущей папки глубиной до depth уровней
+// (отрицательное значение снимает ограничение). Файлы в дерево не включаются
+func (f *Folder) Tree(depth int) (*FolderNode, error) {
+	return f.TreeContext(context.Background(), depth, false)
+}
+
+// TreeContext строит дерево вложенных папок и, если includeFiles равен true, файлов текущей папки
+// глубиной до depth уровней с учетом контекста. Списки папок загружаются параллельно.
+// Если часть папок загрузить не удалось, возвращается построенное дерево без их содержимого
+// и объединенная ошибка с путями этих папок
+func (f *Folder) TreeContext(ctx context.Context, depth int, includeFiles bool) (*FolderNode, error) {
+	root, err := f.client.GetFolderContext(ctx, f.FullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rootNode := &FolderNode{Name: root.Name, FullPath: root.FullPath, Size: root.Size, IsFolder: true}
+	var mu sync.Mutex
+	nodes := map[string]*FolderNode{}
+
+	err = f.client.walkFolders(ctx, root, depth, defaultWalkConcurrency, func(folder *Folder, level int) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		node := rootNode
+		if level > 0 {
+			node = nodes[strings.TrimSuffix(folder.FullPath, "/")]
+		}
+		if node == nil {
+			return nil
+		}
+
+		for _, item := range folder.Items {
+			isFolder := item.IsFolder()
+			if !isFolder && !includeFiles {
+				continue
+			}
+			child := &FolderNode{Name: item.Name, FullPath: item.Home, Size: NewSize(item.Size), IsFolder: isFolder}
+			node.Children = append(node.Children, child)
+			if isFolder {
+				nodes[strings.TrimSuffix(item.Home, "/")] = child
+			}
+		}
+		sort.Slice(node.Children, func(i, j int) bool {
+			return node.Children[i].Name < node.Children[j].Name
+		})
+		return nil
+	})
+	return rootNode, err
+}
+
 // AbortAllAsyncTasks прерывает выполняющиеся асинхронные задачи
 func (f *Folder) AbortAllAsyncTasks() {
 	f.client.AbortAllAsyncTasks()
 }
 
-// updateFolderInfo обновляет информацию о папке, если требуется
+// checkWritable проверяет, что папка доступна для изменения
+func (f *Folder) checkWritable() error {
+	if err := f.checkRemoved(); err != nil {
+		return err
+	}
+	if f.ReadOnly {
+		return &CloudClientError{
+			Message:   "Папка доступна только для чтения",
+			Source:    f.FullPath,
+			ErrorCode: ErrorCodeAccessDenied,
+		}
+	}
+	return nil
+}
+
+// checkRemoved проверяет, что папка не была удалена через Remove
+func (f *Folder) checkRemoved() error {
+	if f.removed {
+		return &CloudClientError{
+			Message:   "Папка удалена",
+			Source:    f.FullPath,
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+	return nil
+}
+
+// Refresh явно обновляет список элементов и информацию о папке с сервера
+func (f *Folder) Refresh() error {
+	if err := f.checkRemoved(); err != nil {
+		return err
+	}
+
+	folder, err := f.client.GetFolder(f.FullPath)
+	if err != nil {
+		return err
+	}
+
+	f.Items = folder.Items
+	f.Size = folder.Size
+	f.PublicLink = folder.PublicLink
+	f.FilesCount = folder.FilesCount
+	f.FoldersCount = folder.FoldersCount
+	f.TreeID = folder.TreeID
+	f.CreatedTimeUTC = folder.CreatedTimeUTC
+	f.LastModifiedTimeUTC = folder.LastModifiedTimeUTC
+	f.lastItemsGettingTime = time.Now()
+	return nil
+}
+
+// updateFolderInfo обновляет информацию о папке, если требуется.
+// Если автоматическое обновление отключено (WithFolderAutoRefresh(false)), список загружается
+// только при первом обращении или принудительно
 func (f *Folder) updateFolderInfo(forceUpdate bool) {
+	if f.client.disableFolderAutoRefresh {
+		if f.Items == nil || forceUpdate {
+			_ = f.Refresh()
+		}
+		return
+	}
+
 	if f.lastItemsGettingTime.IsZero() {
 		f.lastItemsGettingTime = time.Now()
 	}
@@ -247,15 +470,7 @@ func (f *Folder) updateFolderInfo(forceUpdate bool) {
 		currentDiskSpace, err = f.account.GetDiskUsage()
 		return err == nil && currentDiskSpace.Used.DefaultValue != f.prevDiskUsed
 	}()) || forceUpdate {
-		folder, err := f.client.GetFolder(f.FullPath)
-		if err == nil && folder != nil {
-			f.Items = folder.Items
-			f.Size = folder.Size
-			f.PublicLink = folder.PublicLink
-			f.FilesCount = folder.FilesCount
-			f.FoldersCount = folder.FoldersCount
-			f.lastItemsGettingTime = time.Now()
-		}
+		_ = f.Refresh()
 	}
 
 	if currentDiskSpace != nil {
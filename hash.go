@@ -0,0 +1,102 @@
+package mailrucloud
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// mailRuHashPrefix префикс, с которого начинается хешируемая последовательность
+const mailRuHashPrefix = "mrCloud"
+
+// mailRuSmallFileSize размер, до которого хешем файла является само его содержимое
+const mailRuSmallFileSize = 20
+
+// mailRuHasher вычисляет хеш содержимого по алгоритму Mail.ru Облака:
+// для файлов до 20 байт хеш - это содержимое, дополненное нулями до 20 байт,
+// для остальных - SHA1 от "mrCloud" + содержимое + размер в десятичной записи
+type mailRuHasher struct {
+	// sha хеш содержимого с префиксом
+	sha hash.Hash
+	// head первые байты содержимого для файлов до 20 байт
+	head []byte
+	// size количество записанных байт
+	size int64
+}
+
+// newMailRuHasher создает вычислитель хеша Mail.ru
+func newMailRuHasher() *mailRuHasher {
+	h := &mailRuHasher{sha: sha1.New()}
+	h.sha.Write([]byte(mailRuHashPrefix))
+	return h
+}
+
+// Write добавляет содержимое к хешу
+func (h *mailRuHasher) Write(p []byte) (int, error) {
+	if h.size < mailRuSmallFileSize {
+		n := min(len(p), mailRuSmallFileSize-int(h.size))
+		h.head = append(h.head, p[:n]...)
+	}
+	h.size += int64(len(p))
+	return h.sha.Write(p)
+}
+
+// Sum возвращает хеш в виде строки шестнадцатеричных цифр в верхнем регистре.
+// Вызывается один раз после записи всего содержимого
+func (h *mailRuHasher) Sum() string {
+	if h.size <= mailRuSmallFileSize {
+		padded := make([]byte, mailRuSmallFileSize)
+		copy(padded, h.head)
+		return strings.ToUpper(hex.EncodeToString(padded))
+	}
+
+	h.sha.Write([]byte(strconv.FormatInt(h.size, 10)))
+	return strings.ToUpper(hex.EncodeToString(h.sha.Sum(nil)))
+}
+
+// ComputeFileHash вычисляет хеш Mail.ru содержимого потока так же, как его вычисляет сервер
+// (значение File.Hash), и возвращает хеш и размер содержимого. Для содержимого до 20 байт хешем
+// является само содержимое, дополненное нулями до 20 байт; для большего - SHA1 от строки "mrCloud",
+// содержимого и его размера в десятичной записи. Хеш возвращается в верхнем регистре
+func ComputeFileHash(r io.Reader) (string, int64, error) {
+	hasher := newMailRuHasher()
+	buf := defaultCopyBufferPool.Get().(*[]byte)
+	defer defaultCopyBufferPool.Put(buf)
+
+	size, err := io.CopyBuffer(hasher, r, *buf)
+	if err != nil {
+		return "", 0, err
+	}
+	return hasher.Sum(), size, nil
+}
+
+// ComputeFileHashFromPath вычисляет хеш Mail.ru локального файла и возвращает хеш и размер файла
+func ComputeFileHashFromPath(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	return ComputeFileHash(file)
+}
+
+// computeSeekableHash вычисляет хеш Mail.ru size байт потока с текущей позиции и возвращает поток на нее
+func (c *CloudClient) computeSeekableHash(content io.ReadSeeker, size int64) (string, error) {
+	start, err := content.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+
+	hasher := newMailRuHasher()
+	if _, err := c.copyBuffer(hasher, io.LimitReader(content, size)); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(start, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hasher.Sum(), nil
+}
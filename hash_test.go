@@ -0,0 +1,84 @@
+package mailrucloud
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeSeekableHash(t *testing.T) {
+	client := &CloudClient{}
+
+	content := strings.NewReader("skip:" + strings.Repeat("x", 100))
+	_, err := content.Seek(int64(len("skip:")), io.SeekStart)
+	require.NoError(t, err)
+
+	hash, err := client.computeSeekableHash(content, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "195475D61466510189551D1D84AB1BB35EAEBF17", hash)
+
+	position, err := content.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("skip:")), position)
+
+	hash, err = client.computeSeekableHash(strings.NewReader("payload"), 7)
+	require.NoError(t, err)
+	assert.Equal(t, "7061796C6F616400000000000000000000000000", hash)
+}
+
+func TestComputeFileHash(t *testing.T) {
+	// Хеши длинного содержимого получены независимо от библиотеки по эталонному алгоритму:
+	// printf 'mrCloud<содержимое><размер>' | sha1sum
+	vectors := []struct {
+		content string
+		hash    string
+	}{
+		{"", "0000000000000000000000000000000000000000"},
+		{"a", "6100000000000000000000000000000000000000"},
+		{"12345678901234567890", "3132333435363738393031323334353637383930"},
+		{"123456789012345678901", "04722643E3F9B3B881B389D9ACD1B28DFAD56505"},
+		{"The quick brown fox jumps over the lazy dog", "4DE6151D203E813A300D2559278DC95D4F6A7AA4"},
+	}
+
+	for _, v := range vectors {
+		hash, size, err := ComputeFileHash(strings.NewReader(v.content))
+		require.NoError(t, err)
+		assert.Equal(t, v.hash, hash, v.content)
+		assert.Equal(t, int64(len(v.content)), size)
+	}
+
+	path := filepath.Join(t.TempDir(), "fox.txt")
+	require.NoError(t, os.WriteFile(path, []byte(vectors[4].content), 0o600))
+	hash, size, err := ComputeFileHashFromPath(path)
+	require.NoError(t, err)
+	assert.Equal(t, vectors[4].hash, hash)
+	assert.Equal(t, int64(43), size)
+}
+
+func TestComputeFileHashMatchesReference(t *testing.T) {
+	data := make([]byte, 1<<20)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	// Эталонный алгоритм: SHA1 от "mrCloud", содержимого целиком и его размера в десятичной записи
+	reference := sha1.Sum([]byte("mrCloud" + string(data) + strconv.Itoa(len(data))))
+	expected := strings.ToUpper(hex.EncodeToString(reference[:]))
+	assert.Equal(t, "32E20838EE1557DA2305433CEC2D037CB7F7B685", expected)
+
+	// Содержимое читается частями, чтобы проверить потоковое вычисление
+	hash, size, err := ComputeFileHash(iotest.HalfReader(bytes.NewReader(data)))
+	require.NoError(t, err)
+	assert.Equal(t, expected, hash)
+	assert.Equal(t, int64(len(data)), size)
+}
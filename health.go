@@ -0,0 +1,87 @@
+package mailrucloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HealthCheck проверяет подключение к облаку перед выполнением больших задач: действительность
+// авторизации, получение информации о дисковом пространстве, ответ диспетчера и доступность шардов
+// загрузки и скачивания. Шарды проверяются HEAD запросом без передачи данных: шард считается
+// доступным, если ответил кодом меньше 500. Если проверка компонента не удалась, зависящие от него
+// компоненты не проверяются и получают ту же ошибку. Все запросы выполняются с учетом ctx.
+// Ошибка возвращается только при отмене ctx; результаты компонентов содержатся в отчете
+func (c *CloudClient) HealthCheck(ctx context.Context) (*HealthReport, error) {
+	report := &HealthReport{}
+
+	report.Login = checkHealth(func() error {
+		return c.Account.checkAuthorization(ctx, false)
+	})
+	if !report.Login.OK {
+		skipHealth(report.Login.Err, &report.DiskUsage, &report.Dispatcher, &report.UploadShard, &report.DownloadShard)
+		return report, ctx.Err()
+	}
+
+	report.DiskUsage = checkHealth(func() error {
+		usage, err := c.Account.getDiskUsageInternal(ctx, false)
+		report.Usage = usage
+		return err
+	})
+
+	var shards *ShardsList
+	report.Dispatcher = checkHealth(func() error {
+		var err error
+		shards, err = c.getShardsInfoContext(ctx)
+		return err
+	})
+	if !report.Dispatcher.OK {
+		skipHealth(report.Dispatcher.Err, &report.UploadShard, &report.DownloadShard)
+		return report, ctx.Err()
+	}
+
+	report.UploadShard = checkHealth(func() error {
+		return c.pingShard(ctx, shards.Upload, "Upload")
+	})
+	report.DownloadShard = checkHealth(func() error {
+		return c.pingShard(ctx, shards.Get, "Get")
+	})
+	return report, ctx.Err()
+}
+
+// checkHealth выполняет проверку компонента и измеряет ее время
+func checkHealth(check func() error) HealthStatus {
+	start := time.Now()
+	err := check()
+	return HealthStatus{OK: err == nil, Latency: time.Since(start), Err: err}
+}
+
+// skipHealth отмечает компоненты, которые не проверялись из-за ошибки компонента, от которого они зависят
+func skipHealth(err error, statuses ...*HealthStatus) {
+	for _, status := range statuses {
+		status.Err = err
+	}
+}
+
+// pingShard отправляет HEAD запрос первому шарду из списка и проверяет, что он отвечает
+func (c *CloudClient) pingShard(ctx context.Context, shards []*ShardInfo, kind string) error {
+	if len(shards) == 0 || shards[0].URL == "" {
+		return newNoShardAvailableError(kind)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodHead, shards[0].URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	drainAndClose(resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("шард %s ответил статусом %d", kind, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package mailrucloud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	report, err := client.HealthCheck(context.Background())
+	require.NoError(t, err)
+	assert.True(t, report.Login.OK)
+	assert.True(t, report.DiskUsage.OK)
+	require.NotNil(t, report.Usage)
+	assert.Equal(t, int64(8192*1024*1024), report.Usage.Total.DefaultValue)
+	assert.True(t, report.Dispatcher.OK)
+	assert.True(t, report.DownloadShard.OK)
+	assert.False(t, report.UploadShard.OK)
+	assert.ErrorContains(t, report.UploadShard.Err, "503")
+	assert.False(t, report.OK())
+
+	client.Account.authToken = ""
+	report, err = client.HealthCheck(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.Login.OK)
+	assert.False(t, report.Dispatcher.OK)
+	assert.Equal(t, report.Login.Err, report.DownloadShard.Err)
+
+	client.Account.authToken = "token"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err = client.HealthCheck(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, report.Login.OK)
+}
@@ -0,0 +1,34 @@
+package mailrucloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newFakeCloud запускает тестовый сервер, отвечающий на запросы проверки авторизации и диспетчера шардов.
+// Шарды Get, Upload и WeblinkGet указывают на пути /get/, /upload/ и /weblink/ этого же сервера;
+// остальные запросы обрабатываются переданным mux
+func newFakeCloud(t *testing.T, mux *http.ServeMux) (*CloudClient, *httptest.Server) {
+	t.Helper()
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/api/v2/user/space", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"bytes_total":8192,"bytes_used":1024}`))
+	})
+	mux.HandleFunc("/api/v2/dispatcher", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"body":{"get":[{"url":"%[1]s/get/"}],"upload":[{"url":"%[1]s/upload/"}],"weblink_get":[{"url":"%[1]s/weblink"}]}}`, server.URL)
+	})
+
+	account := NewAccount("test@mail.ru", "password", WithEndpoints(server.URL, server.URL))
+	account.authToken = "token"
+	account.initHttpClient(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &CloudClient{Account: account, cancelCtx: ctx, cancelToken: cancel}, server
+}
@@ -0,0 +1,140 @@
+package mailrucloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ListInvites получает список входящих приглашений в общие папки, еще не принятых и не отклоненных
+func (c *CloudClient) ListInvites() ([]Invite, error) {
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+
+	invitesURL := fmt.Sprintf(c.Account.cloudURL()+InvitesURL, c.Account.getAuthToken())
+	req, err := c.newRequest(context.Background(), "GET", invitesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("получение приглашений не удалось: статус %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var invites struct {
+		List []Invite `json:"list"`
+	}
+	if err := c.Account.decodeResponse(body, &invites); err != nil {
+		return nil, err
+	}
+
+	if invites.List == nil {
+		return []Invite{}, nil
+	}
+	return invites.List, nil
+}
+
+// AcceptInvite принимает приглашение и подключает общую папку в корень облака под именем mountName
+// (при совпадении имени папка переименовывается). Возвращает подключенную папку. Если приглашение
+// истекло или отозвано, возвращается ошибка с кодом ErrorCodeInviteExpired
+func (c *CloudClient) AcceptInvite(inviteID, mountName string) (*Folder, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(inviteID)
+	}
+	if mountName == "" {
+		return nil, &CloudClientError{
+			Message:   "Имя подключаемой папки не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	formData := url.Values{}
+	formData.Set("home", c.getPathStartEndSlash(mountName, true, false))
+	formData.Set("invite_token", inviteID)
+	formData.Set("conflict", ConflictModeRename.apiValue())
+	body, err := c.postInviteRequest(InviteMount, inviteID, formData)
+	if err != nil {
+		return nil, err
+	}
+
+	var mountedPath string
+	if err := c.Account.decodeResponse(body, &mountedPath); err != nil {
+		return nil, err
+	}
+	return c.GetFolder(mountedPath)
+}
+
+// RejectInvite отклоняет приглашение в общую папку. Если приглашение истекло или отозвано,
+// возвращается ошибка с кодом ErrorCodeInviteExpired
+func (c *CloudClient) RejectInvite(inviteID string) error {
+	if c.readOnly {
+		return newReadOnlyError(inviteID)
+	}
+	formData := url.Values{}
+	formData.Set("invite_token", inviteID)
+	_, err := c.postInviteRequest(InviteReject, inviteID, formData)
+	return err
+}
+
+// postInviteRequest выполняет операцию с приглашением и возвращает тело успешного ответа
+func (c *CloudClient) postInviteRequest(operationURL, inviteID string, formData url.Values) ([]byte, error) {
+	if inviteID == "" {
+		return nil, &CloudClientError{
+			Message:   "Идентификатор приглашения не может быть пустым",
+			ErrorCode: ErrorCodeInviteExpired,
+		}
+	}
+
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+
+	for k, v := range c.getDefaultFormDataFields() {
+		if formData.Get(k) == "" {
+			formData.Set(k, fmt.Sprintf("%v", v))
+		}
+	}
+
+	req, err := c.newFormRequest(context.Background(), c.Account.cloudURL()+operationURL, formData)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusNotFound, http.StatusGone:
+		return nil, &CloudClientError{
+			Message:   "Приглашение истекло или было отозвано",
+			Source:    inviteID,
+			ErrorCode: ErrorCodeInviteExpired,
+		}
+	default:
+		return nil, fmt.Errorf("операция с приглашением не удалась: статус %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,88 @@
+package mailrucloud
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// UploadManifest загружает содержимое локальной папки localDir в папку облака cloudPath с сохранением
+// структуры вложенных папок. Для каждого файла локально вычисляется хеш Mail.ru, и файл сначала
+// создается по хешу без передачи данных; на шард передаются только файлы, содержимого которых в облаке
+// еще нет. API облака не поддерживает пакетное создание файлов, поэтому запросы выполняются по одному
+// на файл. Существующие файлы с тем же именем перезаписываются, поэтому повторный вызов не создает копий.
+// Ошибки отдельных файлов не прерывают загрузку остальных: возвращается результат по загруженным файлам
+// и объединенная ошибка
+func (c *CloudClient) UploadManifest(localDir, cloudPath string) (*ManifestUploadResult, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(cloudPath)
+	}
+	root, err := c.EnsureFolder(cloudPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ManifestUploadResult{Instant: []string{}, Transferred: []string{}}
+	var errs []error
+	walkErr := filepath.WalkDir(localDir, func(localPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		relative, err := filepath.Rel(localDir, localPath)
+		if err != nil {
+			return err
+		}
+		destPath := path.Join(root.FullPath, filepath.ToSlash(relative))
+
+		if entry.IsDir() {
+			if relative == "." {
+				return nil
+			}
+			if _, err := c.EnsureFolder(destPath); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", localPath, err))
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		uploaded, err := c.uploadManifestFile(localPath, path.Dir(destPath))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", localPath, err))
+			return nil
+		}
+		if uploaded.Instant {
+			result.Instant = append(result.Instant, uploaded.File.FullPath)
+		} else {
+			result.Transferred = append(result.Transferred, uploaded.File.FullPath)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		errs = append(errs, walkErr)
+	}
+	return result, errors.Join(errs...)
+}
+
+// uploadManifestFile загружает локальный файл с попыткой мгновенной загрузки и перезаписью существующего
+func (c *CloudClient) uploadManifestFile(localPath, destFolderPath string) (*UploadResult, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return c.uploadFromStream(filepath.Base(localPath), file, destFolderPath, func(existing *File) ConflictMode {
+		return ConflictModeRewrite
+	}, WithInstantUpload(true))
+}
@@ -0,0 +1,70 @@
+package mailrucloud
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadManifest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	known := strings.Repeat("known dataset content ", 10)
+	unknown := strings.Repeat("new dataset content ", 10)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "known.txt"), []byte(known), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "new.txt"), []byte(unknown), 0o644))
+
+	knownHash, _, err := ComputeFileHash(strings.NewReader(known))
+	require.NoError(t, err)
+	unknownHash, _, err := ComputeFileHash(strings.NewReader(unknown))
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	stored := map[string]bool{knownHash: true}
+	var folders []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder/add", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		home := strings.TrimSuffix(r.PostForm.Get("home"), "/")
+		folders = append(folders, home)
+		_, _ = io.WriteString(w, `{"body":"`+home+`"}`)
+	})
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"type":"folder","home":"`+r.URL.Query().Get("home")+`","list":[]}}`)
+	})
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		mu.Lock()
+		stored[unknownHash] = true
+		mu.Unlock()
+		_, _ = io.WriteString(w, `"`+unknownHash+`"`)
+	})
+	mux.HandleFunc("/api/v2/file/add", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		mu.Lock()
+		defer mu.Unlock()
+		if !stored[r.PostForm.Get("hash")] {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"body":{"home":{"error":"invalid"}}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"body":"`+r.PostForm.Get("home")+`"}`)
+	})
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	result, err := client.UploadManifest(dir, "/dataset")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dataset", "/dataset/sub"}, folders)
+	assert.Equal(t, []string{"/dataset/known.txt"}, result.Instant)
+	assert.Equal(t, []string{"/dataset/sub/new.txt"}, result.Transferred)
+}
@@ -0,0 +1,150 @@
+package mailrucloud
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// duplicateNamePattern имя, измененное сервером при конфликте имен, например "folder (1)"
+var duplicateNamePattern = regexp.MustCompile(`^(.+) \((\d+)\)$`)
+
+// FindDuplicateFolders находит в папке parentPath папки, созданные сервером при конфликте имен
+// (например, "folder (1)" и "folder (2)" рядом с "folder"). Возвращает соответствие полного пути
+// основной папки путям ее дубликатов, отсортированным по имени
+func (c *CloudClient) FindDuplicateFolders(parentPath string) (map[string][]string, error) {
+	parent, err := c.GetFolder(parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make(map[string]string)
+	for _, item := range parent.Items {
+		if item.IsFolder() {
+			folders[item.Name] = item.Home
+		}
+	}
+
+	duplicates := make(map[string][]string)
+	for name, home := range folders {
+		match := duplicateNamePattern.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		if primary, ok := folders[match[1]]; ok {
+			duplicates[primary] = append(duplicates[primary], home)
+		}
+	}
+	for _, paths := range duplicates {
+		sort.Strings(paths)
+	}
+	return duplicates, nil
+}
+
+// MergeFolders переносит содержимое папок others в папку primary и удаляет опустевшие дубликаты.
+// Вложенные папки с совпадающими именами объединяются рекурсивно, а файлы с совпадающими именами
+// остаются на месте (ConflictModeSkip), поэтому папка с пропущенными элементами не удаляется
+func (c *CloudClient) MergeFolders(primary string, others []string) (*MergeResult, error) {
+	return c.MergeFoldersWithConflict(primary, others, ConflictModeSkip)
+}
+
+// MergeFoldersWithConflict переносит содержимое папок others в папку primary с заданным поведением
+// при совпадении имени элемента, не являющегося парой папок: ConflictModeSkip оставляет элемент
+// в дубликате, ConflictModeRename перемещает его с измененным именем, ConflictModeRewrite удаляет
+// элемент основной папки в корзину перед перемещением, ConflictModeStrict прекращает объединение
+// с ошибкой ErrorCodeAlreadyExists. При ошибке возвращается результат уже выполненных операций
+func (c *CloudClient) MergeFoldersWithConflict(primary string, others []string, conflict ConflictMode) (*MergeResult, error) {
+	if c.readOnly {
+		return nil, newReadOnlyError(primary)
+	}
+	if primary == "" {
+		return nil, &CloudClientError{
+			Message:   "Путь основной папки не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	primary = c.getPathStartEndSlash(primary, true, false)
+	sources := make([]string, len(others))
+	for i, other := range others {
+		other = c.getPathStartEndSlash(other, true, false)
+		if other == primary || strings.HasPrefix(other, primary+"/") || strings.HasPrefix(primary, other+"/") {
+			return nil, &CloudClientError{
+				Message:   "Папка-дубликат не может совпадать с основной папкой или быть вложенной в нее",
+				Source:    other,
+				ErrorCode: ErrorCodeNotSupportedOperation,
+			}
+		}
+		sources[i] = other
+	}
+
+	result := &MergeResult{Moved: []string{}, Skipped: []string{}, Removed: []string{}}
+	for _, other := range sources {
+		if err := c.mergeFolderInto(primary, other, conflict, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// mergeFolderInto переносит содержимое папки source в папку dest и удаляет source, если она опустела
+func (c *CloudClient) mergeFolderInto(dest, source string, conflict ConflictMode, result *MergeResult) error {
+	destFolder, err := c.GetFolder(dest)
+	if err != nil {
+		return err
+	}
+	sourceFolder, err := c.GetFolder(source)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]*CloudStructureEntry, len(destFolder.Items))
+	for _, item := range destFolder.Items {
+		existing[item.Name] = item
+	}
+
+	for _, item := range sourceFolder.Items {
+		target, collides := existing[item.Name]
+		switch {
+		case !collides:
+		case target.IsFolder() && item.IsFolder():
+			if err := c.mergeFolderInto(target.Home, item.Home, conflict, result); err != nil {
+				return err
+			}
+			continue
+		case conflict == ConflictModeSkip:
+			result.Skipped = append(result.Skipped, item.Home)
+			continue
+		case conflict == ConflictModeStrict:
+			return &CloudClientError{
+				Message:   fmt.Sprintf("Элемент %s уже существует в папке %s", item.Name, dest),
+				Source:    item.Home,
+				ErrorCode: ErrorCodeAlreadyExists,
+			}
+		case conflict == ConflictModeRewrite:
+			if err := c.Remove(target.Home); err != nil {
+				return err
+			}
+		}
+
+		if _, err := c.Move(item.Home, dest); err != nil {
+			return err
+		}
+		result.Moved = append(result.Moved, item.Home)
+	}
+
+	// Список папки запрашивается повторно, чтобы не удалить элементы, появившиеся во время объединения
+	sourceFolder, err = c.GetFolder(source)
+	if err != nil {
+		return err
+	}
+	if len(sourceFolder.Items) > 0 {
+		return nil
+	}
+	if err := c.Remove(source); err != nil {
+		return err
+	}
+	result.Removed = append(result.Removed, source)
+	return nil
+}
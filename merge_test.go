@@ -0,0 +1,166 @@
+package mailrucloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryCloud хранит дерево папок фейкового облака: путь папки -> имена элементов с признаком папки
+type memoryCloud struct {
+	mu      sync.Mutex
+	folders map[string]map[string]bool
+}
+
+func newMemoryCloud(t *testing.T, folders map[string]map[string]bool) (*CloudClient, *memoryCloud) {
+	t.Helper()
+
+	cloud := &memoryCloud{folders: folders}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		cloud.mu.Lock()
+		defer cloud.mu.Unlock()
+
+		home := path.Clean(r.URL.Query().Get("home"))
+		items, ok := cloud.folders[home]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		list := []map[string]string{}
+		for name, isFolder := range items {
+			kind := "file"
+			if isFolder {
+				kind = "folder"
+			}
+			list = append(list, map[string]string{"type": kind, "name": name, "home": path.Join(home, name)})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i]["name"] < list[j]["name"] })
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"body": map[string]interface{}{"type": "folder", "home": home, "list": list}})
+	})
+	mux.HandleFunc("/api/v2/file/move", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		cloud.mu.Lock()
+		defer cloud.mu.Unlock()
+
+		source, dest := r.PostForm.Get("home"), path.Clean(r.PostForm.Get("folder"))
+		parent, name := path.Dir(source), path.Base(source)
+		isFolder := cloud.folders[parent][name]
+		delete(cloud.folders[parent], name)
+
+		newName := name
+		for i := 1; ; i++ {
+			if _, exists := cloud.folders[dest][newName]; !exists {
+				break
+			}
+			ext := path.Ext(name)
+			newName = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), i, ext)
+		}
+		cloud.folders[dest][newName] = isFolder
+		if isFolder {
+			cloud.rename(source, path.Join(dest, newName))
+		}
+		_, _ = fmt.Fprintf(w, `{"body":%q}`, path.Join(dest, newName))
+	})
+	mux.HandleFunc("/api/v2/file/remove", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		cloud.mu.Lock()
+		defer cloud.mu.Unlock()
+
+		home := r.PostForm.Get("home")
+		delete(cloud.folders[path.Dir(home)], path.Base(home))
+		cloud.rename(home, "")
+		_, _ = fmt.Fprintf(w, `{"body":%q}`, home)
+	})
+	client, _ := newFakeCloud(t, mux)
+	return client, cloud
+}
+
+// rename переносит папку from и все вложенные папки по пути to или удаляет их, если to пустой
+func (m *memoryCloud) rename(from, to string) {
+	for folderPath, items := range m.folders {
+		if folderPath != from && !strings.HasPrefix(folderPath, from+"/") {
+			continue
+		}
+		delete(m.folders, folderPath)
+		if to != "" {
+			m.folders[to+strings.TrimPrefix(folderPath, from)] = items
+		}
+	}
+}
+
+func TestFindDuplicateFolders(t *testing.T) {
+	client, _ := newMemoryCloud(t, map[string]map[string]bool{
+		"/": {"photos": true, "photos (2)": true, "photos (1)": true, "docs (1)": true, "report (1).txt": false, "report.txt": false},
+	})
+
+	duplicates, err := client.FindDuplicateFolders("/")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"/photos": {"/photos (1)", "/photos (2)"}}, duplicates)
+}
+
+func TestMergeFolders(t *testing.T) {
+	newTree := func() map[string]map[string]bool {
+		return map[string]map[string]bool{
+			"/":                {"photos": true, "photos (1)": true},
+			"/photos":          {"a.jpg": false, "2024": true},
+			"/photos/2024":     {"b.jpg": false},
+			"/photos (1)":      {"a.jpg": false, "c.jpg": false, "2024": true, "2025": true},
+			"/photos (1)/2024": {"d.jpg": false},
+			"/photos (1)/2025": {"e.jpg": false},
+		}
+	}
+
+	client, cloud := newMemoryCloud(t, newTree())
+	result, err := client.MergeFolders("/photos", []string{"/photos (1)"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/photos (1)/c.jpg", "/photos (1)/2024/d.jpg", "/photos (1)/2025"}, result.Moved)
+	assert.Equal(t, []string{"/photos (1)/a.jpg"}, result.Skipped)
+	assert.Equal(t, []string{"/photos (1)/2024"}, result.Removed)
+	assert.Equal(t, map[string]bool{"a.jpg": false, "c.jpg": false, "2024": true, "2025": true}, cloud.folders["/photos"])
+	assert.Equal(t, map[string]bool{"a.jpg": false}, cloud.folders["/photos (1)"])
+	assert.Equal(t, map[string]bool{"e.jpg": false}, cloud.folders["/photos/2025"])
+
+	client, cloud = newMemoryCloud(t, newTree())
+	result, err = client.MergeFoldersWithConflict("/photos", []string{"/photos (1)"}, ConflictModeRename)
+	require.NoError(t, err)
+	assert.Empty(t, result.Skipped)
+	assert.Equal(t, []string{"/photos (1)/2024", "/photos (1)"}, result.Removed)
+	assert.Contains(t, cloud.folders["/photos"], "a (1).jpg")
+	assert.NotContains(t, cloud.folders["/"], "photos (1)")
+
+	client, _ = newMemoryCloud(t, newTree())
+	_, err = client.MergeFoldersWithConflict("/photos", []string{"/photos (1)"}, ConflictModeStrict)
+	assert.True(t, hasErrorCode(err, ErrorCodeAlreadyExists))
+
+	_, err = client.MergeFolders("/photos", []string{"/photos/2024"})
+	assert.True(t, hasErrorCode(err, ErrorCodeNotSupportedOperation))
+}
+
+func TestMoveRenameRollback(t *testing.T) {
+	client, cloud := newMemoryCloud(t, map[string]map[string]bool{
+		"/":        {"docs": true, "archive": true},
+		"/docs":    {"a.txt": false},
+		"/archive": {},
+	})
+
+	_, err := client.MoveRename("/docs/a.txt", "/archive", "b:c.txt")
+	assert.True(t, hasErrorCode(err, ErrorCodeInvalidName))
+	assert.Equal(t, map[string]bool{"a.txt": false}, cloud.folders["/docs"])
+	assert.Empty(t, cloud.folders["/archive"])
+
+	// Фейковое облако не поддерживает переименование, поэтому перемещение откатывается
+	_, err = client.MoveRename("/docs/a.txt", "/archive", "b.txt")
+	var decodeErr *ResponseDecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, map[string]bool{"a.txt": false}, cloud.folders["/docs"])
+	assert.Empty(t, cloud.folders["/archive"])
+}
@@ -0,0 +1,64 @@
+package mailrucloud
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// TransferDirectionUpload направление передачи для отправленных на шард загрузки данных
+	TransferDirectionUpload = "upload"
+	// TransferDirectionDownload направление передачи для скачанных данных
+	TransferDirectionDownload = "download"
+	// shardEndpoint имя конечной точки для запросов к шардам, путь которых содержит путь файла
+	shardEndpoint = "shard"
+)
+
+// MetricsCollector получает метрики HTTP запросов и переданных данных клиента. Позволяет передавать
+// метрики в Prometheus, OpenTelemetry и другие системы без зависимости пакета от их библиотек.
+// Методы вызываются из горутин, выполняющих запросы, поэтому реализация должна быть безопасной
+// для одновременного использования и не должна блокироваться надолго
+type MetricsCollector interface {
+	// ObserveRequest вызывается после получения заголовков ответа или сетевой ошибки. endpoint -
+	// путь API (например, "/api/v2/file/add") или "shard" для запросов к шардам загрузки и скачивания,
+	// status - код ответа или 0 при сетевой ошибке, duration - время до получения заголовков ответа
+	ObserveRequest(endpoint string, status int, duration time.Duration)
+	// ObserveTransfer вызывается по мере передачи содержимого файлов. direction -
+	// TransferDirectionUpload или TransferDirectionDownload, bytes - количество байт порции
+	ObserveTransfer(direction string, bytes int64)
+}
+
+// observeRequest передает метрику завершенного запроса получателю метрик
+func (c *CloudClient) observeRequest(req *http.Request, status int, duration time.Duration) {
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.ObserveRequest(metricsEndpoint(req), status, duration)
+}
+
+// observeUpload учитывает отправленные на шард байты в статистике и метриках
+func (c *CloudClient) observeUpload(n int) {
+	c.stats.bytesUploaded.Add(int64(n))
+	if c.metrics != nil {
+		c.metrics.ObserveTransfer(TransferDirectionUpload, int64(n))
+	}
+}
+
+// observeDownload учитывает скачанные байты в статистике и метриках
+func (c *CloudClient) observeDownload(n int) {
+	c.stats.bytesDownloaded.Add(int64(n))
+	if c.metrics != nil {
+		c.metrics.ObserveTransfer(TransferDirectionDownload, int64(n))
+	}
+}
+
+// metricsEndpoint возвращает имя конечной точки запроса для метрик. Пути шардов содержат пути
+// файлов и токены, поэтому для них возвращается общее имя, чтобы не создавать отдельную
+// метрику для каждого файла
+func metricsEndpoint(req *http.Request) string {
+	if strings.HasPrefix(req.URL.Path, "/api/") {
+		return req.URL.Path
+	}
+	return shardEndpoint
+}
@@ -0,0 +1,70 @@
+package mailrucloud
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCloudNameLength максимальная длина имени файла или папки в символах
+const maxCloudNameLength = 255
+
+// cloudForbiddenCharacters символы, недопустимые в именах файлов и папок облака
+var cloudForbiddenCharacters = []rune{'"', '*', '/', ':', '<', '>', '?', '\\', '|'}
+
+// ForbiddenCharacters возвращает символы, недопустимые в именах файлов и папок облака.
+// Управляющие символы (U+0000-U+001F, U+007F) также недопустимы, но в список не входят
+func ForbiddenCharacters() []rune {
+	return append([]rune(nil), cloudForbiddenCharacters...)
+}
+
+// IsValidCloudName проверяет имя файла или папки (без пути) по правилам облака и возвращает
+// CloudClientError с кодом ErrorCodeInvalidName, если имя недопустимо. Имя не может быть пустым,
+// "." или "..", длиннее maxCloudNameLength символов и содержать ForbiddenCharacters или управляющие
+// символы. Такие имена сервер отклоняет или изменяет при создании элемента, поэтому Rename,
+// CreateFolder и загрузка файлов проверяют их заранее. Регистр имен сохраняется как есть
+func IsValidCloudName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return newInvalidNameError(name, "Имя не может быть пустым, \".\" или \"..\"")
+	}
+	if utf8.RuneCountInString(name) > maxCloudNameLength {
+		return newInvalidNameError(name, fmt.Sprintf("Имя не может быть длиннее %d символов", maxCloudNameLength))
+	}
+	if index := strings.IndexFunc(name, isForbiddenNameRune); index != -1 {
+		r, _ := utf8.DecodeRuneInString(name[index:])
+		return newInvalidNameError(name, fmt.Sprintf("Имя содержит недопустимый символ %q", r))
+	}
+	return nil
+}
+
+// validateCloudPath проверяет по правилам облака каждое имя в полном пути
+func validateCloudPath(fullPath string) error {
+	for _, name := range strings.Split(strings.Trim(fullPath, "/"), "/") {
+		if err := IsValidCloudName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isForbiddenNameRune проверяет, что символ недопустим в имени
+func isForbiddenNameRune(r rune) bool {
+	if r < 0x20 || r == 0x7f {
+		return true
+	}
+	for _, forbidden := range cloudForbiddenCharacters {
+		if r == forbidden {
+			return true
+		}
+	}
+	return false
+}
+
+// newInvalidNameError создает ошибку недопустимого имени
+func newInvalidNameError(name, message string) *CloudClientError {
+	return &CloudClientError{
+		Message:   message,
+		Source:    name,
+		ErrorCode: ErrorCodeInvalidName,
+	}
+}
@@ -0,0 +1,46 @@
+package mailrucloud
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidCloudName(t *testing.T) {
+	for _, name := range []string{"отчет.txt", "Фото 2024", ".hidden", "a..b", strings.Repeat("я", maxCloudNameLength)} {
+		assert.NoError(t, IsValidCloudName(name), name)
+	}
+
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`, "a:b", "a*b", "a?b", `a"b`, "a<b", "a>b", "a|b", "a\tb", strings.Repeat("я", maxCloudNameLength+1)} {
+		err := IsValidCloudName(name)
+		assert.True(t, hasErrorCode(err, ErrorCodeInvalidName), "%q", name)
+	}
+
+	chars := ForbiddenCharacters()
+	assert.Contains(t, chars, '/')
+	chars[0] = 'x'
+	assert.NotEqual(t, 'x', ForbiddenCharacters()[0])
+}
+
+func TestInvalidNamesFailFast(t *testing.T) {
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	_, err := client.Rename("/docs/a.txt", "b:c")
+	assert.True(t, hasErrorCode(err, ErrorCodeInvalidName))
+
+	_, err = client.CreateFolder("/docs/bad|name/sub")
+	assert.True(t, hasErrorCode(err, ErrorCodeInvalidName))
+
+	_, err = client.UploadFileFromStream("a?.txt", strings.NewReader("data"), "/docs")
+	require.True(t, hasErrorCode(err, ErrorCodeInvalidName))
+	assert.Zero(t, requests)
+}
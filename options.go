@@ -0,0 +1,352 @@
+package mailrucloud
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CloudClientOption настраивает CloudClient при создании
+type CloudClientOption func(c *CloudClient)
+
+// WithMaxConcurrentRequests ограничивает количество одновременных HTTP запросов клиента, включая
+// списки папок, загрузки и скачивания из любых горутин. Запрос считается выполняющимся до закрытия
+// тела ответа, поэтому открытый поток скачивания занимает место до вызова Close: при малом значении
+// не держите открытыми потоки, выполняя другие операции того же клиента. Запросы сверх лимита
+// ожидают освобождения места или отмены контекста. По умолчанию (0) количество не ограничено
+func WithMaxConcurrentRequests(n int) CloudClientOption {
+	return func(c *CloudClient) {
+		if n > 0 {
+			c.requestSlots = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithReadOnly включает режим только для чтения: запросы, изменяющие облако (загрузка, удаление,
+// переименование, перемещение, копирование, создание папок, публикация, операции с корзиной
+// и приглашениями), не отправляются, а возвращают ошибку с кодом ErrorCodeReadOnly. Чтение
+// списков, информации о файлах и скачивание выполняются как обычно. Защищает от случайных
+// изменений при подключении аналитических инструментов к рабочему аккаунту
+func WithReadOnly(enabled bool) CloudClientOption {
+	return func(c *CloudClient) {
+		c.readOnly = enabled
+	}
+}
+
+// WithMetricsCollector задает получатель метрик HTTP запросов и переданных данных, например
+// адаптер к Prometheus или OpenTelemetry. По умолчанию метрики не собираются
+func WithMetricsCollector(collector MetricsCollector) CloudClientOption {
+	return func(c *CloudClient) {
+		c.metrics = collector
+	}
+}
+
+// WithDownloadResume включает возобновление скачивания при обрыве потока.
+// Если чтение потока, полученного от DownloadFile, завершается ошибкой (кроме io.EOF и отмены контекста),
+// клиент обновляет токен авторизации через Account.RefreshToken, заново получает шард Get и продолжает
+// скачивание Range запросом с последнего полученного байта. Возобновление выполняется не более
+// maxDownloadResumeAttempts раз подряд без прогресса и только если шард отвечает 206 Partial Content
+func WithDownloadResume(enabled bool) CloudClientOption {
+	return func(c *CloudClient) {
+		c.downloadResume = enabled
+	}
+}
+
+// WithCopyBufferSize задает размер буфера, используемого при копировании данных скачивания в поток назначения.
+// Буферы переиспользуются через sync.Pool, что уменьшает количество выделений памяти при множестве
+// одновременных передач. По умолчанию используется буфер defaultCopyBufferSize
+func WithCopyBufferSize(size int) CloudClientOption {
+	return func(c *CloudClient) {
+		if size <= 0 {
+			return
+		}
+		c.copyBufferPool = newCopyBufferPool(size)
+	}
+}
+
+// defaultCopyBufferSize размер буфера копирования по умолчанию (как у io.Copy)
+const defaultCopyBufferSize = 32 * 1024
+
+// defaultCopyBufferPool пул буферов копирования размера по умолчанию
+var defaultCopyBufferPool = newCopyBufferPool(defaultCopyBufferSize)
+
+// newCopyBufferPool создает пул буферов копирования указанного размера
+func newCopyBufferPool(size int) *sync.Pool {
+	return &sync.Pool{
+		New: func() interface{} {
+			buf := make([]byte, size)
+			return &buf
+		},
+	}
+}
+
+// WithUploadRetries задает количество повторных попыток загрузки содержимого на шард после сетевой ошибки
+// или ответа 5xx. Перед повтором поток перематывается к позиции начала загрузки. Потоки без поддержки
+// io.Seeker буферизуются в памяти, поэтому повтор для них также отправляет содержимое целиком;
+// исключение составляют потоки, переданные с WithContentLength: они не буферизуются и не повторяются.
+// По умолчанию повторы отключены
+func WithUploadRetries(retries int) CloudClientOption {
+	return func(c *CloudClient) {
+		if retries > 0 {
+			c.uploadRetries = retries
+		}
+	}
+}
+
+// WithChunkVerification включает проверку целостности загрузки: во время отправки содержимого
+// локально вычисляется его хеш Mail.ru и сравнивается с хешем, который вернул шард, до создания
+// файла в облаке. При расхождении содержимое перематываемых потоков (io.Seeker) отправляется
+// повторно (не менее одного раза и не более WithUploadRetries раз), иначе возвращается ошибка
+// с кодом ErrorCodeUploadFailed. Загрузка выполняется одним запросом, поэтому проверяется
+// и при необходимости повторяется все содержимое целиком.
+// Проверка требует вычисления SHA1 по всем отправляемым данным, что дает заметную нагрузку
+// на процессор при загрузке больших файлов. По умолчанию выключена
+func WithChunkVerification(enabled bool) CloudClientOption {
+	return func(c *CloudClient) {
+		c.chunkVerification = enabled
+	}
+}
+
+// WithFolderAutoRefresh включает или отключает автоматическое обновление списка элементов Folder.
+// По умолчанию GetFiles и GetFolders проверяют использование диска (GetDiskUsage), если с последнего
+// получения списка прошло больше секунды, и перечитывают папку при его изменении. При отключении
+// возвращается кешированный список, а обновить его можно явным вызовом Folder.Refresh
+func WithFolderAutoRefresh(enabled bool) CloudClientOption {
+	return func(c *CloudClient) {
+		c.disableFolderAutoRefresh = !enabled
+	}
+}
+
+// WithProgressSmoothing задает коэффициент сглаживания (0, 1] экспоненциального скользящего среднего,
+// по которому оцениваются ProgressChangeTaskState.BytesPerSecond и EstimatedTimeRemaining.
+// Большее значение быстрее реагирует на изменение скорости, меньшее дает более стабильную оценку.
+// По умолчанию используется defaultProgressSmoothing
+func WithProgressSmoothing(smoothing float64) CloudClientOption {
+	return func(c *CloudClient) {
+		if smoothing > 0 && smoothing <= 1 {
+			c.progressSmoothing = smoothing
+		}
+	}
+}
+
+// AccountOption настраивает Account при создании
+type AccountOption func(a *Account)
+
+// defaultMaxIdleConnsPerHost количество простаивающих соединений на хост, сохраняемых для переиспользования.
+// Значение по умолчанию net/http (2) приводит к открытию новых соединений при параллельных операциях
+const defaultMaxIdleConnsPerHost = 16
+
+// newDefaultTransport создает транспорт HTTP клиента аккаунта на основе http.DefaultTransport
+func newDefaultTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	return transport
+}
+
+// WithMaxIdleConnsPerHost задает количество простаивающих соединений на хост, которые транспорт
+// сохраняет для переиспользования. Увеличение значения ускоряет массовые параллельные операции.
+// По умолчанию используется defaultMaxIdleConnsPerHost
+func WithMaxIdleConnsPerHost(n int) AccountOption {
+	return func(a *Account) {
+		if n > 0 {
+			a.transport.MaxIdleConnsPerHost = n
+		}
+	}
+}
+
+// WithHTTP2 включает или отключает HTTP/2 для соединений аккаунта. По умолчанию HTTP/2 включен
+// и согласуется с сервером через ALPN: параллельные запросы к одному шарду мультиплексируются
+// в одном соединении, что ускоряет массовые операции с небольшими файлами. Количество
+// одновременных потоков в соединении ограничивает сервер (SETTINGS_MAX_CONCURRENT_STREAMS);
+// стандартная библиотека не позволяет задать его на стороне клиента. Отключение HTTP/2 может
+// ускорить передачу больших файлов в несколько потоков, так как каждый поток получает
+// собственное TCP соединение
+func WithHTTP2(enabled bool) AccountOption {
+	return func(a *Account) {
+		a.transport.ForceAttemptHTTP2 = enabled
+		if enabled {
+			a.transport.TLSNextProto = nil
+		} else {
+			// Непустое значение без "h2" отключает HTTP/2 в net/http
+			a.transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		}
+	}
+}
+
+// WithMaxConnsPerHost ограничивает общее количество соединений (активных и простаивающих) на хост.
+// Запросы сверх лимита ожидают освобождения соединения; при HTTP/2 они мультиплексируются
+// в уже открытых соединениях. По умолчанию количество соединений не ограничено
+func WithMaxConnsPerHost(n int) AccountOption {
+	return func(a *Account) {
+		if n > 0 {
+			a.transport.MaxConnsPerHost = n
+		}
+	}
+}
+
+// WithIdleConnTimeout задает время, после которого простаивающее соединение закрывается.
+// По умолчанию используется значение http.DefaultTransport (90 секунд)
+func WithIdleConnTimeout(timeout time.Duration) AccountOption {
+	return func(a *Account) {
+		if timeout > 0 {
+			a.transport.IdleConnTimeout = timeout
+		}
+	}
+}
+
+// WithTLSPinning закрепляет открытые ключи сертификатов серверов: соединение устанавливается, только
+// если цепочка сертификата сервера, прошедшая обычную проверку, содержит сертификат с открытым ключом
+// из pins. Каждый элемент pins - SHA-256 хеш SubjectPublicKeyInfo сертификата в формате DER
+// (32 байта, см. SPKIPin). Проверка применяется ко всем соединениям аккаунта, включая авторизацию
+// и шарды загрузки и скачивания, поэтому закрепляйте ключи промежуточного или корневого центра
+// сертификации, общего для всех серверов Mail.ru, а не ключ отдельного сервера. При несовпадении
+// запрос завершается ошибкой с кодом ErrorCodeCertificatePinMismatch
+func WithTLSPinning(pins [][]byte) AccountOption {
+	return func(a *Account) {
+		if a.transport.TLSClientConfig == nil {
+			a.transport.TLSClientConfig = &tls.Config{}
+		}
+		a.transport.TLSClientConfig.VerifyPeerCertificate = verifyPinnedCertificate(pins)
+	}
+}
+
+// SPKIPin возвращает SHA-256 хеш SubjectPublicKeyInfo сертификата для WithTLSPinning
+func SPKIPin(cert *x509.Certificate) []byte {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return sum[:]
+}
+
+// verifyPinnedCertificate возвращает функцию проверки цепочки сертификатов сервера по закрепленным ключам
+func verifyPinnedCertificate(pins [][]byte) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		for _, chain := range verifiedChains {
+			for _, cert := range chain {
+				pin := SPKIPin(cert)
+				for _, expected := range pins {
+					if bytes.Equal(pin, expected) {
+						return nil
+					}
+				}
+			}
+		}
+		return &CloudClientError{
+			Message:   "Сертификат сервера не соответствует закрепленным открытым ключам",
+			ErrorCode: ErrorCodeCertificatePinMismatch,
+		}
+	}
+}
+
+// defaultAcceptLanguage язык сообщений сервера по умолчанию
+const defaultAcceptLanguage = "ru"
+
+// WithAcceptLanguage задает заголовок Accept-Language всех запросов аккаунта (например, "en"
+// или "en-US,en;q=0.9"), чтобы сообщения сервера возвращались на выбранном языке.
+// По умолчанию используется русский язык ("ru"); пустое значение отключает заголовок.
+// Отдельных заголовков региона API облака не поддерживает
+func WithAcceptLanguage(language string) AccountOption {
+	return func(a *Account) {
+		a.acceptLanguage = language
+	}
+}
+
+// WithEndpoints задает базовые адреса облака и авторизации вместо BaseMailRuCloud и BaseMailRuAuth.
+// Пустое значение оставляет адрес по умолчанию. Публичные ссылки строятся от адреса облака
+func WithEndpoints(cloudBaseURL, authBaseURL string) AccountOption {
+	return func(a *Account) {
+		a.cloudBaseURL = strings.TrimSuffix(cloudBaseURL, "/")
+		a.authBaseURL = strings.TrimSuffix(authBaseURL, "/")
+	}
+}
+
+// WithBusinessAccount настраивает аккаунт Mail.ru для бизнеса: облако BaseMailRuBizCloud
+// и авторизация через BaseMailRuAuth с доменом из email компании
+func WithBusinessAccount() AccountOption {
+	return func(a *Account) {
+		WithEndpoints(BaseMailRuBizCloud, BaseMailRuAuth)(a)
+		WithAuthDomainFromEmail()(a)
+	}
+}
+
+// WithAuthDomain задает домен, передаваемый при авторизации. По умолчанию используется "mail.ru"
+func WithAuthDomain(domain string) AccountOption {
+	return func(a *Account) {
+		a.authDomain = domain
+	}
+}
+
+// WithAuthDomainFromEmail включает передачу при авторизации домена из email (например, "inbox.ru"
+// или корпоративного домена) вместо "mail.ru". Домен, заданный через WithAuthDomain, имеет приоритет
+func WithAuthDomainFromEmail() AccountOption {
+	return func(a *Account) {
+		a.authDomainFromEmail = true
+	}
+}
+
+// WithCredentialProvider задает источник учетных данных, которые запрашиваются при каждом входе
+// (Login и повторный вход после потери сессии). Email и Password аккаунта заменяются полученными значениями
+func WithCredentialProvider(provider CredentialProvider) AccountOption {
+	return func(a *Account) {
+		a.credentials = provider
+	}
+}
+
+// UploadOption настраивает отдельную операцию загрузки
+type UploadOption func(o *uploadOptions)
+
+// uploadOptions параметры операции загрузки
+type uploadOptions struct {
+	// contentLength известный заранее размер содержимого в байтах
+	contentLength int64
+	// instant включает попытку мгновенной загрузки по хешу
+	instant bool
+	// clockSkewTolerance допустимое расхождение часов при сравнении времени модификации в UploadIfNewer
+	clockSkewTolerance time.Duration
+}
+
+// newUploadOptions применяет опции загрузки
+func newUploadOptions(opts []UploadOption) *uploadOptions {
+	options := &uploadOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithContentLength задает размер содержимого, известный заранее (например, resp.ContentLength
+// при передаче http.Response.Body). Поток без поддержки io.Seeker с известным размером передается
+// на шард без буферизации в памяти, но повторные попытки загрузки для него не выполняются.
+// Если поток завершится раньше указанного размера, загрузка вернет ошибку
+func WithContentLength(length int64) UploadOption {
+	return func(o *uploadOptions) {
+		if length > 0 {
+			o.contentLength = length
+		}
+	}
+}
+
+// WithInstantUpload включает мгновенную загрузку: для потоков с поддержкой io.Seeker (и буферизованных
+// потоков) хеш Mail.ru вычисляется локально, и файл сначала создается по хешу без передачи данных.
+// Если такого содержимого в облаке еще нет, выполняется обычная загрузка на шард.
+// Требует дополнительного чтения содержимого и вычисления SHA1. Результат отражается
+// в UploadResult.Instant и UploadResult.BytesTransferred
+func WithInstantUpload(enabled bool) UploadOption {
+	return func(o *uploadOptions) {
+		o.instant = enabled
+	}
+}
+
+// WithClockSkewTolerance задает допустимое расхождение часов локальной машины и сервера для
+// UploadIfNewer: файл загружается, только если локальное время модификации позже удаленного
+// больше чем на tolerance. Не влияет на остальные операции загрузки
+func WithClockSkewTolerance(tolerance time.Duration) UploadOption {
+	return func(o *uploadOptions) {
+		if tolerance > 0 {
+			o.clockSkewTolerance = tolerance
+		}
+	}
+}
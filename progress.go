@@ -0,0 +1,147 @@
+package mailrucloud
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"time"
+)
+
+const (
+	// defaultProgressSmoothing коэффициент сглаживания скользящего среднего скорости по умолчанию
+	defaultProgressSmoothing = 0.3
+	// progressSampleInterval минимальный интервал между замерами скорости и событиями прогресса
+	progressSampleInterval = 200 * time.Millisecond
+)
+
+// progressTracker считает переданные байты и оценивает скорость передачи
+// экспоненциальным скользящим средним по замерам не чаще progressSampleInterval
+type progressTracker struct {
+	// client клиент облака, событие которого вызывается
+	client *CloudClient
+	// operationID уникальный идентификатор передачи
+	operationID string
+	// path путь файла в облаке
+	path string
+	// total общий размер передачи
+	total int64
+	// done количество переданных байт
+	done int64
+	// smoothing коэффициент сглаживания (0, 1]: чем больше, тем сильнее вес последнего замера
+	smoothing float64
+	// speed текущая оценка скорости в байтах в секунду
+	speed float64
+	// sampleTime время последнего замера
+	sampleTime time.Time
+	// sampleBytes количество переданных байт на момент последнего замера
+	sampleBytes int64
+	// now источник текущего времени
+	now func() time.Time
+}
+
+// newProgressTracker создает счетчик прогресса передачи файла path указанного размера
+func newProgressTracker(client *CloudClient, path string, total int64) *progressTracker {
+	smoothing := client.progressSmoothing
+	if smoothing <= 0 || smoothing > 1 {
+		smoothing = defaultProgressSmoothing
+	}
+	t := &progressTracker{
+		client:      client,
+		operationID: newOperationID(),
+		path:        path,
+		total:       total,
+		smoothing:   smoothing,
+		now:         time.Now,
+	}
+	t.restart()
+	return t
+}
+
+// restart начинает отсчет заново, например при повторной попытке загрузки
+func (t *progressTracker) restart() {
+	t.done = 0
+	t.speed = 0
+	t.sampleBytes = 0
+	t.sampleTime = t.now()
+	t.notify()
+}
+
+// add учитывает переданные байты и при необходимости обновляет оценку скорости и вызывает событие
+func (t *progressTracker) add(n int) {
+	t.done += int64(n)
+
+	now := t.now()
+	elapsed := now.Sub(t.sampleTime)
+	if elapsed < progressSampleInterval {
+		return
+	}
+
+	current := float64(t.done-t.sampleBytes) / elapsed.Seconds()
+	if t.speed == 0 {
+		t.speed = current
+	} else {
+		t.speed = t.smoothing*current + (1-t.smoothing)*t.speed
+	}
+	t.sampleTime = now
+	t.sampleBytes = t.done
+	t.notify()
+}
+
+// finish вызывает событие завершения передачи
+func (t *progressTracker) finish() {
+	t.done = t.total
+	t.notify()
+}
+
+// state возвращает текущее состояние передачи
+func (t *progressTracker) state() *ProgressChangeTaskState {
+	state := &ProgressChangeTaskState{
+		TotalBytes:      NewSize(t.total),
+		BytesInProgress: NewSize(t.done),
+		BytesPerSecond:  t.speed,
+		OperationID:     t.operationID,
+		Path:            t.path,
+	}
+	if t.speed > 0 && t.done < t.total {
+		state.EstimatedTimeRemaining = time.Duration(float64(t.total-t.done) / t.speed * float64(time.Second))
+	}
+	return state
+}
+
+// notify вызывает событие изменения прогресса клиента
+func (t *progressTracker) notify() {
+	if t.client.ProgressChangedEvent == nil {
+		return
+	}
+
+	percentage := 100
+	if t.total > 0 {
+		percentage = int(t.done * 100 / t.total)
+	}
+	t.client.ProgressChangedEvent(t.client, &ProgressChangedEventArgs{
+		ProgressPercentage: percentage,
+		State:              t.state(),
+	})
+}
+
+// newOperationID генерирует уникальный идентификатор передачи
+func newOperationID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// progressReader поток чтения, сообщающий о прочитанных байтах счетчику прогресса
+type progressReader struct {
+	reader  io.Reader
+	tracker *progressTracker
+}
+
+// Read читает данные и учитывает их в прогрессе
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.tracker.add(n)
+	}
+	return n, err
+}
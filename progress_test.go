@@ -0,0 +1,55 @@
+package mailrucloud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressTrackerSpeedEstimate(t *testing.T) {
+	var events []*ProgressChangedEventArgs
+	client := &CloudClient{
+		ProgressChangedEvent: func(sender interface{}, e *ProgressChangedEventArgs) {
+			events = append(events, e)
+		},
+	}
+	WithProgressSmoothing(0.5)(client)
+
+	now := time.Unix(0, 0)
+	tracker := newProgressTracker(client, "/file.bin", 1000)
+	tracker.now = func() time.Time { return now }
+	tracker.restart()
+	events = events[:0]
+
+	now = now.Add(time.Second)
+	tracker.add(100)
+	assert.Equal(t, 100.0, tracker.speed)
+
+	now = now.Add(time.Second)
+	tracker.add(300)
+	assert.Equal(t, 200.0, tracker.speed)
+
+	// Замеры чаще progressSampleInterval не меняют оценку
+	now = now.Add(time.Millisecond)
+	tracker.add(100)
+	assert.Equal(t, 200.0, tracker.speed)
+
+	tracker.finish()
+
+	require.Len(t, events, 3)
+	last := events[1].State
+	assert.Equal(t, 10, events[0].ProgressPercentage)
+	assert.Equal(t, 40, events[1].ProgressPercentage)
+	assert.Equal(t, 200.0, last.BytesPerSecond)
+	assert.Equal(t, 3*time.Second, last.EstimatedTimeRemaining)
+	assert.Equal(t, 100, events[2].ProgressPercentage)
+	assert.Zero(t, events[2].State.EstimatedTimeRemaining)
+
+	for _, e := range events {
+		assert.Equal(t, "/file.bin", e.State.Path)
+		assert.Equal(t, events[0].State.OperationID, e.State.OperationID)
+	}
+	assert.NotEqual(t, events[0].State.OperationID, newProgressTracker(client, "/file.bin", 1000).operationID)
+}
@@ -0,0 +1,474 @@
+package mailrucloud
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Кодировщик QR кодов для публичных ссылок: байтовый режим, уровень коррекции ошибок M,
+// версии 1-10 (до 213 байт). Реализация следует ISO/IEC 18004 и не требует внешних зависимостей
+
+const (
+	// qrQuietZone ширина свободной зоны вокруг QR кода в модулях
+	qrQuietZone = 4
+	// qrModuleSize размер модуля QR кода в пикселях PNG
+	qrModuleSize = 8
+)
+
+// qrVersion параметры версии QR кода для уровня коррекции M
+type qrVersion struct {
+	// ecPerBlock количество кодовых слов коррекции в каждом блоке
+	ecPerBlock int
+	// blocks количество кодовых слов данных в каждом блоке
+	blocks []int
+	// align координаты центров выравнивающих узоров
+	align []int
+}
+
+// qrVersionsM параметры версий 1-10 для уровня коррекции M
+var qrVersionsM = []qrVersion{
+	{10, []int{16}, nil},
+	{16, []int{28}, []int{6, 18}},
+	{26, []int{44}, []int{6, 22}},
+	{18, []int{32, 32}, []int{6, 26}},
+	{24, []int{43, 43}, []int{6, 30}},
+	{16, []int{27, 27, 27, 27}, []int{6, 34}},
+	{18, []int{31, 31, 31, 31}, []int{6, 22, 38}},
+	{22, []int{38, 38, 39, 39}, []int{6, 24, 42}},
+	{22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}},
+	{26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}},
+}
+
+// dataCodewords возвращает общее количество кодовых слов данных версии
+func (v qrVersion) dataCodewords() int {
+	total := 0
+	for _, n := range v.blocks {
+		total += n
+	}
+	return total
+}
+
+// encodeQRPNG кодирует данные в QR код и возвращает его изображение PNG
+func encodeQRPNG(data []byte) ([]byte, error) {
+	modules, err := encodeQR(data)
+	if err != nil {
+		return nil, err
+	}
+
+	size := len(modules)
+	side := (size + 2*qrQuietZone) * qrModuleSize
+	img := image.NewGray(image.Rect(0, 0, side, side))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if !modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < qrModuleSize; dy++ {
+				for dx := 0; dx < qrModuleSize; dx++ {
+					img.SetGray((x+qrQuietZone)*qrModuleSize+dx, (y+qrQuietZone)*qrModuleSize+dy, color.Gray{})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeQR кодирует данные в матрицу модулей QR кода (true - темный модуль)
+func encodeQR(data []byte) ([][]bool, error) {
+	number := 0
+	for i, v := range qrVersionsM {
+		countBits := 8
+		if i+1 >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= v.dataCodewords()*8 {
+			number = i + 1
+			break
+		}
+	}
+	if number == 0 {
+		return nil, fmt.Errorf("данные длиной %d байт не помещаются в QR код", len(data))
+	}
+
+	version := qrVersionsM[number-1]
+	codewords := qrInterleave(version, qrDataCodewords(number, version, data))
+
+	q := newQRMatrix(number, version)
+	q.placeData(codewords)
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if penalty := qrPenalty(q.modules); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		// Маска применяется через XOR, поэтому повторное применение ее отменяет
+		q.applyMask(mask)
+	}
+	q.applyMask(bestMask)
+	q.drawFormatBits(bestMask)
+	return q.modules, nil
+}
+
+// qrDataCodewords формирует кодовые слова данных в байтовом режиме с дополнением до емкости версии
+func qrDataCodewords(number int, version qrVersion, data []byte) []byte {
+	var bits qrBitBuffer
+	bits.append(0x4, 4)
+	if number >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacity := version.dataCodewords() * 8
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := make([]byte, capacity/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return codewords
+}
+
+// qrInterleave разбивает данные на блоки, вычисляет для них коды коррекции и чередует кодовые слова
+func qrInterleave(version qrVersion, data []byte) []byte {
+	var dataBlocks, ecBlocks [][]byte
+	maxBlock := 0
+	for _, n := range version.blocks {
+		block := data[:n]
+		data = data[n:]
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, qrReedSolomon(block, version.ecPerBlock))
+		maxBlock = max(maxBlock, n)
+	}
+
+	var result []byte
+	for i := 0; i < maxBlock; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < version.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// qrBitBuffer последовательность битов кодируемых данных
+type qrBitBuffer []bool
+
+// append добавляет младшие count битов value, начиная со старшего
+func (b *qrBitBuffer) append(value, count int) {
+	for i := count - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+// qrGFMultiply умножает элементы поля GF(256) с порождающим многочленом 0x11D
+func qrGFMultiply(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		carry := z >> 7
+		z = z<<1 ^ carry*0x1D
+		z ^= (y >> i & 1) * x
+	}
+	return z
+}
+
+// qrReedSolomon вычисляет ecCount кодовых слов коррекции Рида-Соломона для блока данных
+func qrReedSolomon(data []byte, ecCount int) []byte {
+	// Порождающий многочлен (x - a^0)(x - a^1)...(x - a^(ecCount-1)) без старшего коэффициента
+	generator := make([]byte, ecCount)
+	generator[ecCount-1] = 1
+	root := byte(1)
+	for i := 0; i < ecCount; i++ {
+		for j := 0; j < ecCount; j++ {
+			generator[j] = qrGFMultiply(generator[j], root)
+			if j+1 < ecCount {
+				generator[j] ^= generator[j+1]
+			}
+		}
+		root = qrGFMultiply(root, 0x02)
+	}
+
+	remainder := make([]byte, ecCount)
+	for _, b := range data {
+		factor := b ^ remainder[0]
+		copy(remainder, remainder[1:])
+		remainder[ecCount-1] = 0
+		for i := range remainder {
+			remainder[i] ^= qrGFMultiply(generator[i], factor)
+		}
+	}
+	return remainder
+}
+
+// qrMatrix матрица модулей QR кода с отметками служебных областей
+type qrMatrix struct {
+	size     int
+	modules  [][]bool
+	reserved [][]bool
+}
+
+// newQRMatrix создает матрицу версии number со служебными узорами
+func newQRMatrix(number int, version qrVersion) *qrMatrix {
+	size := 17 + 4*number
+	q := &qrMatrix{size: size, modules: make([][]bool, size), reserved: make([][]bool, size)}
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.reserved[i] = make([]bool, size)
+	}
+
+	// Синхронизирующие линии
+	for i := 0; i < size; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+
+	// Поисковые узоры с разделителями
+	for _, corner := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || x >= size || y < 0 || y >= size {
+					continue
+				}
+				dist := max(qrAbs(dx), qrAbs(dy))
+				q.set(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	// Выравнивающие узоры, кроме пересекающихся с поисковыми
+	last := len(version.align) - 1
+	for i, y := range version.align {
+		for j, x := range version.align {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(x+dx, y+dy, max(qrAbs(dx), qrAbs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Области формата резервируются до выбора маски
+	q.drawFormatBits(0)
+
+	if number >= 7 {
+		remainder := number
+		for i := 0; i < 12; i++ {
+			remainder = remainder<<1 ^ (remainder>>11)*0x1F25
+		}
+		bits := number<<12 | remainder
+		for i := 0; i < 18; i++ {
+			dark := (bits>>i)&1 == 1
+			a, b := size-11+i%3, i/3
+			q.set(a, b, dark)
+			q.set(b, a, dark)
+		}
+	}
+	return q
+}
+
+// set устанавливает служебный модуль (x - столбец, y - строка)
+func (q *qrMatrix) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.reserved[y][x] = true
+}
+
+// drawFormatBits записывает информацию о формате (уровень M и маска) в обе области формата
+func (q *qrMatrix) drawFormatBits(mask int) {
+	// Уровень коррекции M кодируется битами 00
+	data := mask
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = remainder<<1 ^ (remainder>>9)*0x537
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.set(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.size-15+i, bit(i))
+	}
+	// Темный модуль
+	q.set(8, q.size-8, true)
+}
+
+// placeData размещает кодовые слова зигзагом по парам столбцов снизу вверх и сверху вниз
+func (q *qrMatrix) placeData(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < q.size; vert++ {
+			y := vert
+			if upward {
+				y = q.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if q.reserved[y][x] {
+					continue
+				}
+				if i < len(codewords)*8 {
+					q.modules[y][x] = (codewords[i/8]>>(7-i%8))&1 == 1
+				}
+				i++
+			}
+		}
+	}
+}
+
+// applyMask инвертирует модули данных по условию маски
+func (q *qrMatrix) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if !q.reserved[y][x] && qrMaskCondition(mask, x, y) {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// qrMaskCondition проверяет условие маски для модуля (x - столбец, y - строка)
+func qrMaskCondition(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// qrPenalty оценивает матрицу по правилам выбора маски: чем меньше штраф, тем лучше читается код
+func qrPenalty(modules [][]bool) int {
+	size := len(modules)
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return modules[x][y]
+		}
+		return modules[y][x]
+	}
+
+	penalty := 0
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	for _, transpose := range []bool{false, true} {
+		for y := 0; y < size; y++ {
+			// Серии из пяти и более модулей одного цвета
+			run := 1
+			for x := 1; x <= size; x++ {
+				if x < size && at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += 3 + run - 5
+				}
+				run = 1
+			}
+
+			// Узоры, похожие на поисковые
+			for x := 0; x+11 <= size; x++ {
+				for _, pattern := range finderLike {
+					matched := true
+					for k, dark := range pattern {
+						if at(x+k, y, transpose) != dark {
+							matched = false
+							break
+						}
+					}
+					if matched {
+						penalty += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if modules[y][x] {
+				dark++
+			}
+			// Блоки 2x2 одного цвета
+			if x+1 < size && y+1 < size {
+				c := modules[y][x]
+				if modules[y][x+1] == c && modules[y+1][x] == c && modules[y+1][x+1] == c {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	// Отклонение доли темных модулей от 50%
+	percent := dark * 100 / (size * size)
+	penalty += qrAbs(percent-50) / 5 * 10
+	return penalty
+}
+
+// qrAbs возвращает модуль целого числа
+func qrAbs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,117 @@
+package mailrucloud
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQRReedSolomon(t *testing.T) {
+	// Пример версии 1-M из описания стандарта
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	assert.Equal(t, []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}, qrReedSolomon(data, 10))
+}
+
+func TestQRFormatAndVersionBits(t *testing.T) {
+	q := newQRMatrix(7, qrVersionsM[6])
+	q.drawFormatBits(0)
+
+	// Формат уровня M с маской 0: 101010000010010, биты с 14 по 0
+	format := ""
+	for i := 14; i >= 0; i-- {
+		x, y := q.size-1-i, 8
+		if i >= 8 {
+			x, y = 8, q.size-15+i
+		}
+		format += map[bool]string{true: "1", false: "0"}[q.modules[y][x]]
+	}
+	assert.Equal(t, "101010000010010", format)
+
+	// Информация о версии 7: 000111110010010100, биты с 17 по 0
+	version := ""
+	for i := 17; i >= 0; i-- {
+		version += map[bool]string{true: "1", false: "0"}[q.modules[i/3][q.size-11+i%3]]
+	}
+	assert.Equal(t, "000111110010010100", version)
+}
+
+func TestQRDataRoundTrip(t *testing.T) {
+	link := "https://cloud.mail.ru/public/Ab3d/XyZ12345q"
+	modules, err := encodeQR([]byte(link))
+	require.NoError(t, err)
+	require.Len(t, modules, 33)
+
+	number := (len(modules) - 17) / 4
+	version := qrVersionsM[number-1]
+
+	// Маска определяется по информации о формате
+	reference := newQRMatrix(number, version)
+	mask := -1
+	for candidate := 0; candidate < 8; candidate++ {
+		reference.drawFormatBits(candidate)
+		if reference.modules[8][0] == modules[8][0] && reference.modules[8][1] == modules[8][1] &&
+			reference.modules[8][2] == modules[8][2] && reference.modules[8][3] == modules[8][3] &&
+			reference.modules[8][4] == modules[8][4] && reference.modules[8][5] == modules[8][5] &&
+			reference.modules[8][7] == modules[8][7] && reference.modules[8][8] == modules[8][8] {
+			mask = candidate
+		}
+	}
+	require.NotEqual(t, -1, mask)
+
+	// Чтение кодовых слов тем же зигзагом после снятия маски
+	var bits qrBitBuffer
+	for right := len(modules) - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < len(modules); vert++ {
+			y := vert
+			if upward {
+				y = len(modules) - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if !reference.reserved[y][x] {
+					bits = append(bits, modules[y][x] != qrMaskCondition(mask, x, y))
+				}
+			}
+		}
+	}
+	expected := qrInterleave(version, qrDataCodewords(number, version, []byte(link)))
+	actual := make([]byte, len(expected))
+	for i := range actual {
+		for k := 0; k < 8; k++ {
+			if bits[i*8+k] {
+				actual[i] |= 0x80 >> k
+			}
+		}
+	}
+	assert.Equal(t, expected, actual)
+
+	// Данные в байтовом режиме: режим 0100, длина и байты ссылки
+	data := qrDataCodewords(number, version, []byte(link))
+	assert.Equal(t, byte(0x40|len(link)>>4), data[0])
+}
+
+func TestFilePublicLinkQR(t *testing.T) {
+	file := &File{CloudStructureEntryBase: CloudStructureEntryBase{FullPath: "/a.txt"}}
+	_, err := file.PublicLinkQR()
+	assert.True(t, hasErrorCode(err, ErrorCodePublicLinkNotExists))
+
+	file.PublicLink = "https://cloud.mail.ru/public/Ab3d/XyZ12345q"
+	data, err := file.PublicLinkQR()
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, (33+2*qrQuietZone)*qrModuleSize, img.Bounds().Dx())
+
+	_, err = encodeQR([]byte(strings.Repeat("a", 214)))
+	assert.Error(t, err)
+	_, err = encodeQR([]byte(strings.Repeat("a", 213)))
+	assert.NoError(t, err)
+}
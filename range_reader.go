@@ -0,0 +1,235 @@
+package mailrucloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// rangeReader поток чтения удаленного файла с поддержкой перемещения через Range запросы
+type rangeReader struct {
+	// client клиент облака для выполнения запросов
+	client *CloudClient
+	// ctx контекст запросов
+	ctx context.Context
+	// url адрес файла
+	url string
+	// size полный размер файла
+	size int64
+	// offset текущая позиция чтения
+	offset int64
+	// body тело текущего ответа, открытого с позиции offset
+	body io.ReadCloser
+	// done вызывается при первом закрытии потока, например для завершения передачи (может быть nil)
+	done func()
+	// doneOnce гарантирует однократный вызов done
+	doneOnce sync.Once
+}
+
+// newRangeReader открывает удаленный файл с начала и определяет его размер
+func newRangeReader(ctx context.Context, client *CloudClient, fileURL string) (*rangeReader, error) {
+	r := &rangeReader{
+		client: client,
+		ctx:    ctx,
+		url:    fileURL,
+		size:   -1,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Read читает данные с текущей позиции, открывая новый Range запрос при необходимости
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if r.size >= 0 && r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+// Seek устанавливает позицию следующего чтения
+func (r *rangeReader) Seek(offset int64, whence int) (int64, error) {
+	var newOffset int64
+	switch whence {
+	case io.SeekStart:
+		newOffset = offset
+	case io.SeekCurrent:
+		newOffset = r.offset + offset
+	case io.SeekEnd:
+		if r.size < 0 {
+			return 0, errors.New("размер файла неизвестен")
+		}
+		newOffset = r.size + offset
+	default:
+		return 0, errors.New("некорректное значение whence")
+	}
+
+	if newOffset < 0 {
+		return 0, errors.New("отрицательная позиция")
+	}
+
+	if newOffset != r.offset {
+		r.closeBody()
+		r.offset = newOffset
+	}
+	return newOffset, nil
+}
+
+// Close закрывает текущий ответ
+func (r *rangeReader) Close() error {
+	r.closeBody()
+	if r.done != nil {
+		r.doneOnce.Do(r.done)
+	}
+	return nil
+}
+
+// closeBody закрывает тело текущего ответа
+func (r *rangeReader) closeBody() {
+	if r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+}
+
+// open выполняет Range запрос с текущей позиции
+func (r *rangeReader) open() error {
+	req, err := r.client.newRequest(r.ctx, "GET", r.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", r.offset))
+
+	resp, err := r.client.do(req)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if total := parseContentRangeTotal(resp.Header.Get("Content-Range")); total >= 0 {
+			r.size = total
+		}
+	case http.StatusOK:
+		// Сервер проигнорировал Range и вернул файл целиком
+		if r.offset > 0 {
+			drainAndClose(resp.Body)
+			return &CloudClientError{
+				Message:   "Сервер не поддерживает частичное скачивание",
+				Source:    r.url,
+				ErrorCode: ErrorCodeNotSupportedOperation,
+			}
+		}
+		r.size = resp.ContentLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		drainAndClose(resp.Body)
+		return io.EOF
+	case http.StatusNotFound:
+		drainAndClose(resp.Body)
+		return &CloudClientError{
+			Message:   "Файл не существует в облаке",
+			Source:    r.url,
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	default:
+		drainAndClose(resp.Body)
+		return fmt.Errorf("скачивание не удалось: статус %d", resp.StatusCode)
+	}
+
+	r.body = resp.Body
+	return nil
+}
+
+// parseContentRangeTotal извлекает полный размер из заголовка Content-Range вида "bytes 0-99/1000"
+func parseContentRangeTotal(contentRange string) int64 {
+	slash := strings.LastIndex(contentRange, "/")
+	if slash == -1 {
+		return -1
+	}
+	total, err := strconv.ParseInt(contentRange[slash+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return total
+}
+
+// maxDownloadResumeAttempts максимальное количество попыток возобновления скачивания подряд без прогресса
+const maxDownloadResumeAttempts = 3
+
+// resumingReader поток скачивания, возобновляющий передачу с последнего полученного байта при ошибке чтения
+type resumingReader struct {
+	// body тело текущего ответа
+	body io.ReadCloser
+	// reopen открывает поток заново начиная с указанного смещения
+	reopen func(offset int64) (io.ReadCloser, error)
+	// offset количество уже прочитанных байт
+	offset int64
+	// attempts количество попыток возобновления подряд без прогресса
+	attempts int
+	// onRetry вызывается перед попыткой возобновления (может быть nil)
+	onRetry func(attempt int, err error)
+	// onGiveUp вызывается, когда возобновление прекращено после неудачных попыток (может быть nil)
+	onGiveUp func(attempts int, err error)
+}
+
+// Read читает данные, возобновляя скачивание при обрыве потока
+func (r *resumingReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			r.attempts = 0
+		}
+
+		if err == nil || err == io.EOF || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return n, err
+		}
+
+		if r.attempts >= maxDownloadResumeAttempts {
+			if r.onGiveUp != nil {
+				r.onGiveUp(r.attempts, err)
+			}
+			return n, err
+		}
+		r.attempts++
+		if r.onRetry != nil {
+			r.onRetry(r.attempts, err)
+		}
+
+		r.body.Close()
+		body, reopenErr := r.reopen(r.offset)
+		if reopenErr != nil {
+			r.body = io.NopCloser(strings.NewReader(""))
+			err = fmt.Errorf("%w (возобновление не удалось: %v)", err, reopenErr)
+			if r.onGiveUp != nil {
+				r.onGiveUp(r.attempts, err)
+			}
+			return n, err
+		}
+		r.body = body
+
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close закрывает текущий поток
+func (r *resumingReader) Close() error {
+	return r.body.Close()
+}
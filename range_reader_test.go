@@ -0,0 +1,127 @@
+package mailrucloud
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient создает клиент, выполняющий запросы через HTTP клиент тестового сервера
+func newTestClient(server *httptest.Server) *CloudClient {
+	account := NewAccount("test@mail.ru", "password")
+	account.httpClient = server.Client()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CloudClient{Account: account, cancelCtx: ctx, cancelToken: cancel}
+}
+
+func TestRangeReaderSeek(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 10))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file.txt", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	reader, err := newRangeReader(context.Background(), client, server.URL)
+	require.NoError(t, err)
+	defer reader.Close()
+	assert.Equal(t, int64(len(content)), reader.size)
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(reader, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "01234", string(buf))
+
+	offset, err := reader.Seek(-3, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(97), offset)
+
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "789", string(rest))
+
+	_, err = reader.Seek(10, io.SeekStart)
+	require.NoError(t, err)
+	_, err = io.ReadFull(reader, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "01234", string(buf))
+}
+
+// failingReader возвращает ошибку после чтения всех данных
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestResumingReader(t *testing.T) {
+	content := "hello, resumable world"
+	var offsets []int64
+	reader := &resumingReader{
+		body: io.NopCloser(&failingReader{data: []byte(content[:7])}),
+		reopen: func(offset int64) (io.ReadCloser, error) {
+			offsets = append(offsets, offset)
+			return io.NopCloser(strings.NewReader(content[offset:])), nil
+		},
+	}
+
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, []int64{7}, offsets)
+}
+
+func TestOpenPublicLinkUnknownSizeTracksTransfer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(DownloadTokenURL, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"body":"key"}}`)
+	})
+	mux.HandleFunc("/weblink/abc/file.txt", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.URL.Query().Get("key"))
+		// Ответ без Content-Length и без поддержки Range
+		w.(http.Flusher).Flush()
+		_, _ = io.WriteString(w, "public content")
+	})
+	client, server := newFakeCloud(t, mux)
+
+	stream, size, err := client.OpenPublicLink(server.URL + "/public/abc/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), size)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- client.Shutdown(context.Background())
+	}()
+	select {
+	case <-result:
+		t.Fatal("Shutdown завершился до закрытия потока публичной ссылки")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	data, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	assert.Equal(t, "public content", string(data))
+	require.NoError(t, stream.Close())
+	require.NoError(t, stream.Close())
+	select {
+	case err := <-result:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown не завершился после закрытия потока")
+	}
+}
@@ -0,0 +1,68 @@
+package mailrucloud
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Shutdown останавливает клиент: новые загрузки и скачивания отклоняются с ошибкой
+// ErrorCodeClientShutdown, а уже начатые передачи продолжаются до завершения. Скачивание
+// считается завершенным, когда закрыт полученный поток. Если ctx истекает раньше, оставшиеся
+// передачи прерываются через AbortAllAsyncTasks и возвращается ошибка ctx.
+// В отличие от AbortAllAsyncTasks, который сразу прерывает все запросы, Shutdown дает
+// текущим передачам завершиться и не позволяет начать новые. Повторный вызов безопасен
+func (c *CloudClient) Shutdown(ctx context.Context) error {
+	c.transfersMu.Lock()
+	c.shuttingDown = true
+	c.transfersMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.transfers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		c.AbortAllAsyncTasks()
+		return ctx.Err()
+	}
+}
+
+// beginTransfer регистрирует новую передачу. После Shutdown возвращает ошибку.
+// При успешной регистрации вызывающий обязан вызвать c.transfers.Done
+func (c *CloudClient) beginTransfer() error {
+	c.transfersMu.Lock()
+	defer c.transfersMu.Unlock()
+
+	if c.shuttingDown {
+		return &CloudClientError{
+			Message:   "Клиент остановлен и не принимает новые операции",
+			ErrorCode: ErrorCodeClientShutdown,
+		}
+	}
+	c.transfers.Add(1)
+	return nil
+}
+
+// trackTransfer связывает завершение зарегистрированной передачи с закрытием потока скачивания
+func (c *CloudClient) trackTransfer(stream io.ReadCloser) io.ReadCloser {
+	return &transferReadCloser{ReadCloser: stream, done: c.transfers.Done}
+}
+
+// transferReadCloser поток скачивания, отмечающий передачу завершенной при первом закрытии
+type transferReadCloser struct {
+	io.ReadCloser
+	done func()
+	once sync.Once
+}
+
+// Close закрывает поток и завершает передачу
+func (r *transferReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.done)
+	return err
+}
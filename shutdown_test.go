@@ -0,0 +1,62 @@
+package mailrucloud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownWaitsForTransfers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get/file.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	stream, _, err := client.DownloadFile("/file.txt")
+	require.NoError(t, err)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- client.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-result:
+		t.Fatal("Shutdown завершился до закрытия потока скачивания")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_, _, err = client.DownloadFile("/file.txt")
+	assert.True(t, hasErrorCode(err, ErrorCodeClientShutdown))
+
+	require.NoError(t, stream.Close())
+	require.NoError(t, stream.Close())
+	select {
+	case err := <-result:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown не завершился после закрытия потока")
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/get/file.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	stream, _, err := client.DownloadFile("/file.txt")
+	require.NoError(t, err)
+	defer stream.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, client.Shutdown(ctx), context.DeadlineExceeded)
+	assert.Error(t, client.cancelCtx.Err())
+}
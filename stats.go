@@ -0,0 +1,34 @@
+package mailrucloud
+
+import "sync/atomic"
+
+// transferCounters счетчики статистики передачи, безопасные для одновременного использования
+type transferCounters struct {
+	bytesUploaded   atomic.Int64
+	bytesDownloaded atomic.Int64
+	requests        atomic.Int64
+	retries         atomic.Int64
+	failures        atomic.Int64
+}
+
+// Stats возвращает статистику передачи данных с момента создания клиента или последнего вызова
+// ResetStats. Счетчики обновляются атомарно, поэтому метод можно вызывать во время передач из
+// других горутин; значения разных счетчиков снимаются не одновременно
+func (c *CloudClient) Stats() TransferStats {
+	return TransferStats{
+		BytesUploaded:   c.stats.bytesUploaded.Load(),
+		BytesDownloaded: c.stats.bytesDownloaded.Load(),
+		Requests:        c.stats.requests.Load(),
+		Retries:         c.stats.retries.Load(),
+		Failures:        c.stats.failures.Load(),
+	}
+}
+
+// ResetStats обнуляет статистику передачи данных
+func (c *CloudClient) ResetStats() {
+	c.stats.bytesUploaded.Store(0)
+	c.stats.bytesDownloaded.Store(0)
+	c.stats.requests.Store(0)
+	c.stats.retries.Store(0)
+	c.stats.failures.Store(0)
+}
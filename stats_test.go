@@ -0,0 +1,123 @@
+package mailrucloud
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferStats(t *testing.T) {
+	uploads := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		uploads++
+		if uploads == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, `"7061796C6F616400000000000000000000000000"`)
+	})
+	mux.HandleFunc("/get/file.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "downloaded content")
+	})
+	client, server := newFakeCloud(t, mux)
+	WithUploadRetries(1)(client)
+
+	_, err := client.uploadToShard(server.URL+"/upload/", "/file.txt", strings.NewReader("payload"), int64(len("payload")))
+	require.NoError(t, err)
+
+	stream, _, err := client.DownloadFile("/file.txt")
+	require.NoError(t, err)
+	_, err = io.ReadAll(stream)
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+
+	stats := client.Stats()
+	assert.Equal(t, int64(2*len("payload")), stats.BytesUploaded)
+	assert.Equal(t, int64(len("downloaded content")), stats.BytesDownloaded)
+	assert.Equal(t, int64(1), stats.Retries)
+	assert.Equal(t, int64(1), stats.Failures)
+	// Две попытки загрузки, запрос шардов и скачивание
+	assert.Equal(t, int64(4), stats.Requests)
+
+	client.ResetStats()
+	assert.Equal(t, TransferStats{}, client.Stats())
+}
+
+// recordingMetrics получатель метрик, сохраняющий наблюдения для проверки
+type recordingMetrics struct {
+	mu        sync.Mutex
+	requests  []string
+	transfers map[string]int64
+}
+
+func (m *recordingMetrics) ObserveRequest(endpoint string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, fmt.Sprintf("%s %d", endpoint, status))
+}
+
+func (m *recordingMetrics) ObserveTransfer(direction string, bytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transfers[direction] += bytes
+}
+
+func TestMetricsCollector(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, `"7061796C6F616400000000000000000000000000"`)
+	})
+	mux.HandleFunc("/get/file.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "downloaded content")
+	})
+	client, server := newFakeCloud(t, mux)
+	metrics := &recordingMetrics{transfers: map[string]int64{}}
+	WithMetricsCollector(metrics)(client)
+
+	_, err := client.uploadToShard(server.URL+"/upload/", "/file.txt", strings.NewReader("payload"), int64(len("payload")))
+	require.NoError(t, err)
+
+	stream, _, err := client.DownloadFile("/file.txt")
+	require.NoError(t, err)
+	_, err = io.ReadAll(stream)
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+
+	assert.Equal(t, []string{"shard 200", "/api/v2/dispatcher 200", "shard 200"}, metrics.requests)
+	assert.Equal(t, map[string]int64{
+		TransferDirectionUpload:   int64(len("payload")),
+		TransferDirectionDownload: int64(len("downloaded content")),
+	}, metrics.transfers)
+}
+
+func TestAccountRequestsUseClientLimiterAndMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(AuthTokenURL, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"token":"fresh"}}`)
+	})
+	fake, _ := newFakeCloud(t, mux)
+	metrics := &recordingMetrics{transfers: map[string]int64{}}
+	client, err := NewCloudClientNoCheck(fake.Account, WithMaxConcurrentRequests(1), WithMetricsCollector(metrics))
+	require.NoError(t, err)
+
+	require.NoError(t, client.Account.RefreshToken())
+	_, err = client.Account.GetDiskUsage()
+	require.NoError(t, err)
+
+	assert.Equal(t, "fresh", client.Account.Token())
+	// GetDiskUsage перед запросом использования диска проверяет авторизацию тем же запросом
+	assert.Equal(t, []string{AuthTokenURL + " 200", "/api/v2/user/space 200", "/api/v2/user/space 200"}, metrics.requests)
+	assert.Equal(t, int64(3), client.Stats().Requests)
+	// Тела ответов закрыты, место в семафоре освобождено
+	assert.Empty(t, client.requestSlots)
+}
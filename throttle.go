@@ -0,0 +1,132 @@
+package mailrucloud
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// bandwidthLimiter ограничивает суммарную скорость передачи всех потоков одного направления.
+// Нулевое значение готово к использованию и не ограничивает скорость
+type bandwidthLimiter struct {
+	mu sync.Mutex
+	// bytesPerSecond допустимая скорость передачи (0 - без ограничения)
+	bytesPerSecond int64
+	// next момент, начиная с которого можно передавать следующие данные
+	next time.Time
+}
+
+// setLimit задает допустимую скорость передачи
+func (l *bandwidthLimiter) setLimit(bytesPerSecond int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if bytesPerSecond < 0 {
+		bytesPerSecond = 0
+	}
+	l.bytesPerSecond = bytesPerSecond
+	l.next = time.Time{}
+}
+
+// limit возвращает допустимую скорость передачи
+func (l *bandwidthLimiter) limit() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.bytesPerSecond
+}
+
+// wait резервирует передачу n байт и ожидает, пока она станет допустимой, или отмены ctx
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.bytesPerSecond <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.bytesPerSecond) * float64(time.Second)))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttledReader поток чтения, скорость которого ограничена bandwidthLimiter
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *bandwidthLimiter
+	// observe учитывает прочитанные байты в статистике и метриках клиента
+	observe func(n int)
+}
+
+// Read читает данные порциями не больше секундного лимита, ожидая разрешения ограничителя
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if limit := r.limiter.limit(); limit > 0 && int64(len(p)) > limit {
+		p = p[:limit]
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.observe(n)
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// throttledReadCloser поток чтения с ограничением скорости и закрытием исходного потока
+type throttledReadCloser struct {
+	throttledReader
+	closer io.Closer
+}
+
+// Close закрывает исходный поток
+func (r *throttledReadCloser) Close() error {
+	return r.closer.Close()
+}
+
+// SetBandwidthLimit ограничивает скорость загрузки и скачивания одинаковым значением в байтах в секунду.
+// Ограничение действует суммарно для всех одновременных передач одного направления, в том числе
+// уже начатых. Значение 0 снимает ограничение. Ожидание прерывается при отмене задач клиента
+func (c *CloudClient) SetBandwidthLimit(bytesPerSecond int64) {
+	c.SetUploadBandwidthLimit(bytesPerSecond)
+	c.SetDownloadBandwidthLimit(bytesPerSecond)
+}
+
+// SetUploadBandwidthLimit ограничивает скорость загрузки в байтах в секунду (0 - без ограничения)
+func (c *CloudClient) SetUploadBandwidthLimit(bytesPerSecond int64) {
+	c.uploadLimiter.setLimit(bytesPerSecond)
+}
+
+// SetDownloadBandwidthLimit ограничивает скорость скачивания в байтах в секунду (0 - без ограничения)
+func (c *CloudClient) SetDownloadBandwidthLimit(bytesPerSecond int64) {
+	c.downloadLimiter.setLimit(bytesPerSecond)
+}
+
+// throttleUpload оборачивает поток загрузки ограничителем скорости
+func (c *CloudClient) throttleUpload(reader io.Reader) io.Reader {
+	return &throttledReader{ctx: c.cancelCtx, reader: reader, limiter: &c.uploadLimiter, observe: c.observeUpload}
+}
+
+// throttleDownload оборачивает поток скачивания ограничителем скорости
+func (c *CloudClient) throttleDownload(body io.ReadCloser) io.ReadCloser {
+	return &throttledReadCloser{
+		throttledReader: throttledReader{ctx: c.cancelCtx, reader: body, limiter: &c.downloadLimiter, observe: c.observeDownload},
+		closer:          body,
+	}
+}
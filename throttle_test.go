@@ -0,0 +1,54 @@
+package mailrucloud
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottledReader(t *testing.T) {
+	client := &CloudClient{cancelCtx: context.Background()}
+	client.SetDownloadBandwidthLimit(10000)
+
+	reader := client.throttleDownload(io.NopCloser(strings.NewReader(strings.Repeat("x", 3000))))
+	start := time.Now()
+	var total int
+	buf := make([]byte, 1000)
+	for {
+		n, err := reader.Read(buf)
+		total += n
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3000, total)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	client.SetDownloadBandwidthLimit(0)
+	start = time.Now()
+	_, err := io.Copy(io.Discard, client.throttleDownload(io.NopCloser(bytes.NewReader(make([]byte, 1<<20)))))
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestThrottledReaderCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &CloudClient{cancelCtx: ctx}
+	client.SetUploadBandwidthLimit(10)
+
+	reader := client.throttleUpload(strings.NewReader(strings.Repeat("x", 100)))
+	buf := make([]byte, 10)
+	_, err := reader.Read(buf)
+	require.NoError(t, err)
+
+	cancel()
+	_, err = reader.Read(buf)
+	assert.ErrorIs(t, err, context.Canceled)
+}
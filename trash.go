@@ -0,0 +1,119 @@
+package mailrucloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetTrash получает список элементов в корзине
+func (c *CloudClient) GetTrash() ([]*TrashItem, error) {
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+
+	trashURL := fmt.Sprintf(c.Account.cloudURL()+TrashURL, c.Account.getAuthToken())
+	req, err := c.newRequest(context.Background(), "GET", trashURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("получение корзины не удалось: статус %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var trash struct {
+		List []*TrashItem `json:"list"`
+	}
+	if err := c.Account.decodeResponse(body, &trash); err != nil {
+		return nil, err
+	}
+
+	if trash.List == nil {
+		return []*TrashItem{}, nil
+	}
+	return trash.List, nil
+}
+
+// RestoreFromTrash восстанавливает элемент из корзины в папку, из которой он был удален.
+// При совпадении имени с существующим элементом восстановленный элемент переименовывается
+func (c *CloudClient) RestoreFromTrash(item *TrashItem) error {
+	if c.readOnly {
+		return newReadOnlyError(TrashRestore)
+	}
+	if item == nil || item.FullPath == "" {
+		return &CloudClientError{
+			Message:   "Элемент корзины не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	formData := url.Values{}
+	formData.Set("path", item.FullPath)
+	formData.Set("restore_revision", strconv.FormatInt(item.Revision, 10))
+	formData.Set("conflict", ConflictModeRename.apiValue())
+	return c.postTrashRequest(TrashRestore, formData)
+}
+
+// EmptyTrash безвозвратно удаляет все элементы корзины
+func (c *CloudClient) EmptyTrash() error {
+	if c.readOnly {
+		return newReadOnlyError(TrashEmpty)
+	}
+	return c.postTrashRequest(TrashEmpty, url.Values{})
+}
+
+// DeletePermanently безвозвратно удаляет отдельный элемент. API облака не поддерживает безвозвратное
+// удаление отдельного элемента: удаленные элементы всегда попадают в корзину, которую можно только
+// очистить целиком через EmptyTrash. Поэтому метод всегда возвращает ошибку с кодом
+// ErrorCodeNotSupportedOperation и ничего не удаляет
+func (c *CloudClient) DeletePermanently(sourceFullPath string) error {
+	return &CloudClientError{
+		Message:   "Безвозвратное удаление отдельного элемента не поддерживается. Используйте MoveToTrash и EmptyTrash",
+		Source:    sourceFullPath,
+		ErrorCode: ErrorCodeNotSupportedOperation,
+	}
+}
+
+// postTrashRequest выполняет POST запрос операции с корзиной
+func (c *CloudClient) postTrashRequest(operationURL string, formData url.Values) error {
+	if err := c.checkAuthorization(); err != nil {
+		return err
+	}
+
+	for k, v := range c.getDefaultFormDataFields() {
+		if formData.Get(k) == "" {
+			formData.Set(k, fmt.Sprintf("%v", v))
+		}
+	}
+
+	req, err := c.newFormRequest(context.Background(), c.Account.cloudURL()+operationURL, formData)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("операция с корзиной не удалась: статус %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package mailrucloud
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -20,6 +22,62 @@ const (
 	StorageUnitTB
 )
 
+// AuthState определяет состояние авторизации аккаунта
+type AuthState int
+
+const (
+	// AuthStateLoggedIn вход выполнен успешно
+	AuthStateLoggedIn AuthState = iota
+	// AuthStateRefreshed токен авторизации обновлен
+	AuthStateRefreshed
+	// AuthStateLost авторизация потеряна (сессия истекла или токен недействителен)
+	AuthStateLost
+)
+
+// VirusScanStatus статус антивирусной проверки файла (поле virus_scan метаданных файла)
+type VirusScanStatus string
+
+const (
+	// VirusScanPass проверка завершена, угроз не найдено
+	VirusScanPass VirusScanStatus = "pass"
+	// VirusScanFail проверка завершена, файл заражен; публичные ссылки на него блокируются
+	VirusScanFail VirusScanStatus = "fail"
+	// VirusScanNotChecked проверка еще не выполнена
+	VirusScanNotChecked VirusScanStatus = "not_checked"
+)
+
+// IsTerminal возвращает true, если проверка завершена
+func (s VirusScanStatus) IsTerminal() bool {
+	return s == VirusScanPass || s == VirusScanFail
+}
+
+// ConflictMode определяет поведение при совпадении имени создаваемого элемента с существующим
+type ConflictMode int
+
+const (
+	// ConflictModeRename переименовать новый элемент (например, "file (1).txt")
+	ConflictModeRename ConflictMode = iota
+	// ConflictModeRewrite перезаписать существующий элемент
+	ConflictModeRewrite
+	// ConflictModeStrict вернуть ошибку, если элемент уже существует
+	ConflictModeStrict
+	// ConflictModeSkip не выполнять операцию и оставить существующий элемент без изменений.
+	// Обрабатывается на стороне клиента
+	ConflictModeSkip
+)
+
+// apiValue возвращает значение параметра conflict для API облака
+func (m ConflictMode) apiValue() string {
+	switch m {
+	case ConflictModeRewrite:
+		return "rewrite"
+	case ConflictModeStrict, ConflictModeSkip:
+		return "strict"
+	default:
+		return "rename"
+	}
+}
+
 // Size определяет размер элемента в облаке
 type Size struct {
 	// DefaultValue значение по умолчанию в байтах
@@ -39,6 +97,29 @@ func NewSize(sourceValue int64) *Size {
 	return size
 }
 
+// MarshalJSON сериализует размер как число байт. Нормализованные поля не сохраняются, так как
+// однозначно вычисляются из DefaultValue
+func (s *Size) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.DefaultValue)
+}
+
+// UnmarshalJSON восстанавливает размер из числа байт и вычисляет нормализованные поля через NewSize.
+// Для совместимости с ранее сохраненными данными принимается и объект с полем DefaultValue
+func (s *Size) UnmarshalJSON(data []byte) error {
+	var value int64
+	if err := json.Unmarshal(data, &value); err != nil {
+		var legacy struct {
+			DefaultValue int64
+		}
+		if legacyErr := json.Unmarshal(data, &legacy); legacyErr != nil {
+			return err
+		}
+		value = legacy.DefaultValue
+	}
+	*s = *NewSize(value)
+	return nil
+}
+
 func (s *Size) setNormalizedValue() {
 	if s.DefaultValue < 1024 {
 		s.NormalizedType = StorageUnitByte
@@ -69,6 +150,24 @@ type DiskUsage struct {
 	Free *Size
 }
 
+// PercentUsed возвращает долю занятого места в процентах (0-100). Для диска нулевого размера возвращает 0
+func (d *DiskUsage) PercentUsed() float64 {
+	return d.percentOfTotal(d.Used)
+}
+
+// PercentFree возвращает долю свободного места в процентах (0-100). Для диска нулевого размера возвращает 0
+func (d *DiskUsage) PercentFree() float64 {
+	return d.percentOfTotal(d.Free)
+}
+
+// percentOfTotal вычисляет долю value от общего размера диска в процентах
+func (d *DiskUsage) percentOfTotal(value *Size) float64 {
+	if d == nil || d.Total == nil || value == nil || d.Total.DefaultValue <= 0 {
+		return 0
+	}
+	return float64(value.DefaultValue) / float64(d.Total.DefaultValue) * 100
+}
+
 // CloudStructureEntryBase базовый класс элемента структуры облака
 type CloudStructureEntryBase struct {
 	// Name имя элемента
@@ -89,6 +188,28 @@ type CloudStructureEntryBase struct {
 	client *CloudClient
 }
 
+// WeblinkToken возвращает токен публичной ссылки (часть после PublicLink) или пустую строку, если элемент не опубликован
+func (e *CloudStructureEntryBase) WeblinkToken() string {
+	prefix := e.account.publicLinkPrefix()
+	if !strings.HasPrefix(e.PublicLink, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(e.PublicLink, prefix)
+}
+
+// ShareURL возвращает полную публичную ссылку для общего доступа или пустую строку, если элемент не опубликован
+func (e *CloudStructureEntryBase) ShareURL() string {
+	return e.account.buildPublicLink(e.WeblinkToken())
+}
+
+// unixTimeUTC конвертирует время UNIX в UTC, возвращая нулевое время для 0
+func unixTimeUTC(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
 // History определяет историю модификации файла
 type History struct {
 	// ID уникальный ID текущей истории
@@ -113,6 +234,179 @@ type History struct {
 	SizeBytes int64 `json:"size"`
 }
 
+// PublishOptions параметры публичной ссылки
+type PublishOptions struct {
+	// MaxDownloads максимальное количество скачиваний по ссылке, после которого ссылка перестает работать.
+	// 0 означает отсутствие лимита. API не возвращает оставшееся количество скачиваний в ответе публикации
+	MaxDownloads int
+}
+
+// RenameOptions дополнительные параметры переименования
+type RenameOptions struct {
+	// Republish повторно публикует элемент, если до переименования он был опубликован, а после
+	// переименования сервер не вернул его публичную ссылку. Новая ссылка может отличаться от прежней
+	Republish bool
+}
+
+// MoveOptions дополнительные параметры перемещения и копирования
+type MoveOptions struct {
+	// CreateDest создает папку назначения вместе с недостающими родительскими папками, если она
+	// не существует. По умолчанию перемещение в несуществующую папку завершается ошибкой
+	CreateDest bool
+}
+
+// UploadResult результат загрузки файла
+type UploadResult struct {
+	// File загруженный файл с метаданными, полученными от сервера
+	File *File
+	// SentHash хеш, полученный от шарда загрузки и переданный при создании файла
+	SentHash string
+	// SentSize размер переданного содержимого в байтах
+	SentSize int64
+	// HashMismatch указывает, что сервер сообщил хеш или размер, отличающиеся от переданных
+	HashMismatch bool
+	// Instant указывает, что содержимое уже было в облаке и файл создан по хешу без передачи данных
+	Instant bool
+	// BytesTransferred количество байт, фактически переданных на шард загрузки
+	BytesTransferred int64
+}
+
+// FolderNode узел дерева папок, построенного Folder.Tree
+type FolderNode struct {
+	// Name имя элемента
+	Name string
+	// FullPath полный путь элемента в облаке
+	FullPath string
+	// Size размер элемента
+	Size *Size
+	// IsFolder указывает, что узел является папкой
+	IsFolder bool
+	// Children вложенные папки и файлы, отсортированные по имени (пусто для файлов и папок глубже ограничения)
+	Children []*FolderNode
+}
+
+// ManifestUploadResult результат загрузки локальной папки через UploadManifest
+type ManifestUploadResult struct {
+	// Instant пути файлов в облаке, созданных по хешу без передачи содержимого
+	Instant []string
+	// Transferred пути файлов в облаке, содержимое которых было передано на шард
+	Transferred []string
+}
+
+// FolderSize размер папки с учетом всего ее содержимого
+type FolderSize struct {
+	// Name имя папки
+	Name string
+	// FullPath полный путь папки в облаке
+	FullPath string
+	// Size суммарный размер содержимого папки, включая вложенные папки
+	Size *Size
+	// Depth глубина папки относительно корня обхода (1 для непосредственно вложенных папок)
+	Depth int
+	// FilesCount количество файлов непосредственно в папке
+	FilesCount int
+	// FoldersCount количество папок непосредственно в папке
+	FoldersCount int
+}
+
+// FolderEventKind вид изменения элемента папки, обнаруженного WatchFolder
+type FolderEventKind int
+
+const (
+	// FolderEventAdded элемент появился в папке
+	FolderEventAdded FolderEventKind = iota
+	// FolderEventRemoved элемент удален из папки
+	FolderEventRemoved
+	// FolderEventChanged изменились содержимое, размер, ревизия или публичная ссылка элемента
+	FolderEventChanged
+	// FolderEventError не удалось получить список папки; наблюдение продолжается
+	FolderEventError
+)
+
+// FolderEvent изменение элемента папки, обнаруженное WatchFolder
+type FolderEvent struct {
+	// Kind вид изменения
+	Kind FolderEventKind
+	// Entry запись элемента: текущая для FolderEventAdded и FolderEventChanged,
+	// последняя известная для FolderEventRemoved, nil для FolderEventError
+	Entry *CloudStructureEntry
+	// Err ошибка получения списка папки для FolderEventError
+	Err error
+}
+
+// Invite входящее приглашение в общую папку другого пользователя
+type Invite struct {
+	// ID токен приглашения, передаваемый в AcceptInvite и RejectInvite
+	ID string `json:"invite_token"`
+	// Name имя общей папки
+	Name string `json:"name"`
+	// FullPath путь общей папки в облаке владельца
+	FullPath string `json:"home"`
+	// Owner владелец папки, отправивший приглашение
+	Owner *Owner `json:"owner"`
+	// Access уровень доступа (read_only или read_write)
+	Access string `json:"access"`
+	// Size размер папки в байтах
+	Size int64 `json:"size"`
+}
+
+// ReadOnly возвращает true, если приглашение дает доступ только для чтения
+func (i *Invite) ReadOnly() bool {
+	return i.Access == accessReadOnly
+}
+
+// TrashItem элемент корзины
+type TrashItem struct {
+	// Name имя элемента
+	Name string `json:"name"`
+	// FullPath путь элемента в корзине, используемый для восстановления
+	FullPath string `json:"home"`
+	// DeletedFrom папка, из которой элемент был удален
+	DeletedFrom string `json:"deleted_from"`
+	// DeletedAt время удаления в секундах Unix
+	DeletedAt int64 `json:"deleted_at"`
+	// Revision ревизия удаленного элемента, используемая для восстановления
+	Revision int64 `json:"rev"`
+	// SizeBytes размер элемента в байтах
+	SizeBytes int64 `json:"size"`
+	// Type тип элемента: "file" или "folder"
+	Type string `json:"type"`
+	// Hash хеш файла
+	Hash string `json:"hash"`
+}
+
+// DeletedAtUTC возвращает время удаления элемента в UTC
+func (t *TrashItem) DeletedAtUTC() time.Time {
+	return unixTimeUTC(t.DeletedAt)
+}
+
+// IsFolder указывает, что удаленный элемент является папкой
+func (t *TrashItem) IsFolder() bool {
+	return t.Type == "folder"
+}
+
+// LinkStats статистика публичной ссылки
+type LinkStats struct {
+	// PublicLink публичная ссылка элемента
+	PublicLink string
+	// Views количество просмотров по ссылке
+	Views int64
+	// Downloads количество скачиваний по ссылке
+	Downloads int64
+	// LastAccessTimeUTC время последнего обращения по ссылке в UTC (нулевое, если сервер его не вернул)
+	LastAccessTimeUTC time.Time
+}
+
+// RestoreResult результат обратимого восстановления файла из истории
+type RestoreResult struct {
+	// File восстановленный файл
+	File *File
+	// PreviousRevision номер ревизии версии файла, которая была текущей до восстановления
+	PreviousRevision int64
+	// BackupPath путь к копии версии до восстановления (пустой, если копия не создавалась)
+	BackupPath string
+}
+
 // ProgressChangedEventArgs аргументы события изменения прогресса
 type ProgressChangedEventArgs struct {
 	// ProgressPercentage процент прогресса
@@ -127,6 +421,14 @@ type ProgressChangeTaskState struct {
 	TotalBytes *Size
 	// BytesInProgress байты в процессе для текущей операции
 	BytesInProgress *Size
+	// BytesPerSecond сглаженная оценка скорости передачи в байтах в секунду (0, пока замеров нет)
+	BytesPerSecond float64
+	// EstimatedTimeRemaining оценка оставшегося времени передачи (0, если скорость еще неизвестна)
+	EstimatedTimeRemaining time.Duration
+	// OperationID уникальный идентификатор передачи, одинаковый для всех ее событий (в том числе повторных попыток)
+	OperationID string
+	// Path путь файла в облаке, к которому относится передача
+	Path string
 }
 
 // Rate информация о тарифе
@@ -188,11 +490,15 @@ type ShardInfo struct {
 
 // ShardsList список различных типов шардов
 type ShardsList struct {
-	Video             []*ShardInfo `json:"video"`
-	ViewDirect        []*ShardInfo `json:"view_direct"`
-	WeblinkView       []*ShardInfo `json:"weblink_view"`
-	WeblinkVideo      []*ShardInfo `json:"weblink_video"`
-	WeblinkGet        []*ShardInfo `json:"weblink_get"`
+	Video        []*ShardInfo `json:"video"`
+	ViewDirect   []*ShardInfo `json:"view_direct"`
+	WeblinkView  []*ShardInfo `json:"weblink_view"`
+	WeblinkVideo []*ShardInfo `json:"weblink_video"`
+	WeblinkGet   []*ShardInfo `json:"weblink_get"`
+	// Stock шард скачивания файлов по ссылкам вида cloud.mail.ru/stock/..., которые почта создает
+	// для больших вложений писем. Это не публичная галерея: API облака не позволяет публиковать
+	// в него файлы, поэтому библиотека шард не использует. Поле сохранено для полноты ответа
+	// диспетчера и совместимости
 	Stock             []*ShardInfo `json:"stock"`
 	WeblinkThumbnails []*ShardInfo `json:"weblink_thumbnails"`
 	Web               []*ShardInfo `json:"web"`
@@ -232,5 +538,82 @@ type CloudStructureEntry struct {
 	Time      int64                  `json:"time"`
 	VirusScan string                 `json:"virus_scan"`
 	Hash      string                 `json:"hash"`
+	Owner     *Owner                 `json:"owner"`
+	Access    string                 `json:"access"`
 	List      []*CloudStructureEntry `json:"list"`
+	// Счетчики публичной ссылки, возвращаются сервером только для опубликованных элементов
+	WeblinkViews      int64 `json:"weblink_views"`
+	WeblinkDownloads  int64 `json:"weblink_downloads"`
+	WeblinkAccessTime int64 `json:"weblink_access_time"`
+}
+
+// IsFolder указывает, что запись является папкой (включая смонтированные общие папки)
+func (e *CloudStructureEntry) IsFolder() bool {
+	return e.Type == "folder" || e.Kind == "folder" || e.Kind == "mounted" || e.Kind == "shared"
+}
+
+// Owner владелец общей папки
+type Owner struct {
+	// Email email владельца
+	Email string `json:"email"`
+	// Name имя владельца
+	Name string `json:"name"`
+}
+
+// accessReadOnly значение поля access для общих папок, доступных только для чтения
+const accessReadOnly = "read_only"
+
+// TransferStats накопленная статистика передачи данных клиента
+type TransferStats struct {
+	// BytesUploaded количество байт, отправленных на шарды загрузки, включая повторные попытки
+	BytesUploaded int64
+	// BytesDownloaded количество байт, прочитанных из потоков скачивания
+	BytesDownloaded int64
+	// Requests количество отправленных HTTP запросов
+	Requests int64
+	// Retries количество повторных попыток загрузки, скачивания и создания папок
+	Retries int64
+	// Failures количество запросов, завершившихся сетевой ошибкой или ответом 5xx
+	Failures int64
+}
+
+// HealthStatus результат проверки одного компонента в HealthReport
+type HealthStatus struct {
+	// OK указывает, что компонент работает
+	OK bool
+	// Latency время проверки компонента
+	Latency time.Duration
+	// Err ошибка проверки (nil, если компонент работает)
+	Err error
+}
+
+// HealthReport результат проверки подключения к облаку через HealthCheck
+type HealthReport struct {
+	// Login действительность авторизации
+	Login HealthStatus
+	// DiskUsage получение информации о дисковом пространстве
+	DiskUsage HealthStatus
+	// Dispatcher получение списка шардов от диспетчера
+	Dispatcher HealthStatus
+	// UploadShard доступность шарда загрузки
+	UploadShard HealthStatus
+	// DownloadShard доступность шарда скачивания
+	DownloadShard HealthStatus
+	// Usage использование диска (nil, если его не удалось получить)
+	Usage *DiskUsage
+}
+
+// OK проверяет, что все компоненты работают
+func (r *HealthReport) OK() bool {
+	return r.Login.OK && r.DiskUsage.OK && r.Dispatcher.OK && r.UploadShard.OK && r.DownloadShard.OK
+}
+
+// MergeResult результат объединения папок через MergeFolders
+type MergeResult struct {
+	// Moved исходные пути элементов, перемещенных в основную папку
+	Moved []string
+	// Skipped пути элементов, оставленных в папках-дубликатах из-за конфликта имен
+	Skipped []string
+	// Removed пути папок-дубликатов, удаленных после перемещения всего содержимого
+	Removed []string
 }
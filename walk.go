@@ -0,0 +1,194 @@
+package mailrucloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultWalkConcurrency количество одновременных запросов списка папок при обходе дерева по умолчанию
+const defaultWalkConcurrency = 4
+
+// walkFolders обходит дерево папок, начиная с уже загруженной папки root, и вызывает visit для root
+// и каждой загруженной вложенной папки. depth - глубина папки относительно root (0 для root).
+// Папки глубже maxDepth не загружаются (отрицательное значение снимает ограничение).
+// Списки папок запрашиваются параллельно, но не более concurrency запросов одновременно, поэтому
+// visit может вызываться из разных горутин. Родительская папка всегда посещается раньше вложенных.
+// Ошибка загрузки папки не прерывает обход остальных ветвей: все ошибки, включая ошибки visit,
+// объединяются через errors.Join. Отмена ctx прекращает загрузку еще не запрошенных папок
+func (c *CloudClient) walkFolders(ctx context.Context, root *Folder, maxDepth, concurrency int, visit func(folder *Folder, depth int) error) error {
+	if concurrency <= 0 {
+		concurrency = defaultWalkConcurrency
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, concurrency)
+	)
+
+	addError := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	var walk func(folder *Folder, depth int)
+	walk = func(folder *Folder, depth int) {
+		if err := visit(folder, depth); err != nil {
+			addError(err)
+			return
+		}
+		if maxDepth >= 0 && depth >= maxDepth {
+			return
+		}
+
+		for _, item := range folder.Items {
+			if !item.IsFolder() {
+				continue
+			}
+
+			wg.Add(1)
+			go func(path string) {
+				defer wg.Done()
+
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					addError(fmt.Errorf("%s: %w", path, ctx.Err()))
+					return
+				}
+				child, err := c.GetFolderContext(ctx, path)
+				<-sem
+
+				if err != nil {
+					addError(fmt.Errorf("%s: %w", path, err))
+					return
+				}
+				walk(child, depth+1)
+			}(item.Home)
+		}
+	}
+
+	walk(root, 0)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// ListModifiedSince возвращает файлы папки path, измененные позже since. Если recursive равен true,
+// просматриваются и все вложенные папки. Используются только данные списков папок, поэтому
+// для каждой папки выполняется один запрос. Файлы отсортированы по полному пути
+func (c *CloudClient) ListModifiedSince(path string, since time.Time, recursive bool) ([]*File, error) {
+	return c.ListModifiedSinceContext(context.Background(), path, since, recursive)
+}
+
+// ListModifiedSinceContext возвращает файлы, измененные позже since, с учетом контекста.
+// Если часть вложенных папок получить не удалось, возвращаются найденные файлы и объединенная ошибка
+func (c *CloudClient) ListModifiedSinceContext(ctx context.Context, path string, since time.Time, recursive bool) ([]*File, error) {
+	maxDepth := 0
+	if recursive {
+		maxDepth = -1
+	}
+	return c.collectFiles(ctx, path, maxDepth, func(file *File) bool {
+		return file.LastModifiedTimeUTC.After(since)
+	})
+}
+
+// ListAllFiles рекурсивно собирает все файлы папки root и всех вложенных папок в плоский список,
+// отсортированный по полному пути. Подходит для построения локального индекса облака:
+// для каждой папки выполняется один запрос, списки загружаются параллельно
+func (c *CloudClient) ListAllFiles(root string) ([]*File, error) {
+	return c.ListAllFilesContext(context.Background(), root)
+}
+
+// ListAllFilesContext рекурсивно собирает все файлы root с учетом контекста. Если часть вложенных
+// папок получить не удалось, возвращаются найденные файлы и объединенная ошибка
+func (c *CloudClient) ListAllFilesContext(ctx context.Context, root string) ([]*File, error) {
+	return c.collectFiles(ctx, root, -1, func(file *File) bool { return true })
+}
+
+// collectFiles обходит папку path до глубины maxDepth и возвращает файлы, для которых match
+// вернула true, отсортированные по полному пути, вместе с объединенной ошибкой обхода
+func (c *CloudClient) collectFiles(ctx context.Context, path string, maxDepth int, match func(file *File) bool) ([]*File, error) {
+	root, err := c.GetFolderContext(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	files := []*File{}
+	err = c.walkFolders(ctx, root, maxDepth, defaultWalkConcurrency, func(folder *Folder, depth int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, item := range folder.Items {
+			if item.IsFolder() {
+				continue
+			}
+			if file := newFileFromEntry(item, c); match(file) {
+				files = append(files, file)
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].FullPath < files[j].FullPath
+	})
+	return files, err
+}
+
+// StorageBreakdown возвращает вложенные папки root до глубины depth (1 - только непосредственно
+// вложенные) с размером всего их содержимого, отсортированные по убыванию размера. Помогает найти,
+// что занимает место в облаке. Размер папки сообщает сервер, поэтому содержимое папок глубже depth
+// не загружается
+func (c *CloudClient) StorageBreakdown(root string, depth int) ([]FolderSize, error) {
+	return c.StorageBreakdownContext(context.Background(), root, depth)
+}
+
+// StorageBreakdownContext возвращает размеры вложенных папок с учетом контекста. Списки папок
+// загружаются параллельно; если часть папок получить не удалось, возвращаются найденные размеры
+// и объединенная ошибка
+func (c *CloudClient) StorageBreakdownContext(ctx context.Context, root string, depth int) ([]FolderSize, error) {
+	if depth < 1 {
+		depth = 1
+	}
+
+	rootFolder, err := c.GetFolderContext(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	sizes := []FolderSize{}
+	err = c.walkFolders(ctx, rootFolder, depth-1, defaultWalkConcurrency, func(folder *Folder, level int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, item := range folder.Items {
+			if !item.IsFolder() {
+				continue
+			}
+			child := newFolderFromEntry(item, c)
+			sizes = append(sizes, FolderSize{
+				Name:         child.Name,
+				FullPath:     child.FullPath,
+				Size:         child.Size,
+				Depth:        level + 1,
+				FilesCount:   child.FilesCount,
+				FoldersCount: child.FoldersCount,
+			})
+		}
+		return nil
+	})
+
+	sort.SliceStable(sizes, func(i, j int) bool {
+		if sizes[i].Size.DefaultValue != sizes[j].Size.DefaultValue {
+			return sizes[i].Size.DefaultValue > sizes[j].Size.DefaultValue
+		}
+		return sizes[i].FullPath < sizes[j].FullPath
+	})
+	return sizes, err
+}
@@ -0,0 +1,128 @@
+package mailrucloud
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListModifiedSince(t *testing.T) {
+	folders := map[string]string{
+		"/backup/":     `{"type":"folder","home":"/backup","list":[{"type":"file","name":"old.txt","home":"/backup/old.txt","mtime":1000},{"type":"file","name":"new.txt","home":"/backup/new.txt","mtime":3000},{"type":"folder","name":"sub","home":"/backup/sub"}]}`,
+		"/backup/sub/": `{"type":"folder","home":"/backup/sub","list":[{"type":"file","name":"deep.txt","home":"/backup/sub/deep.txt","mtime":4000}]}`,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":`+folders[r.URL.Query().Get("home")]+`}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	since := time.Unix(2000, 0)
+	files, err := client.ListModifiedSince("/backup", since, false)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "/backup/new.txt", files[0].FullPath)
+
+	files, err = client.ListModifiedSince("/backup", since, true)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "/backup/new.txt", files[0].FullPath)
+	assert.Equal(t, "/backup/sub/deep.txt", files[1].FullPath)
+}
+
+func TestStorageBreakdown(t *testing.T) {
+	folders := map[string]string{
+		"/":        `{"type":"folder","home":"/","list":[{"type":"folder","name":"photos","home":"/photos","size":5000},{"type":"folder","name":"docs","home":"/docs","size":300},{"type":"file","name":"a.txt","home":"/a.txt","size":100000}]}`,
+		"/photos/": `{"type":"folder","home":"/photos","list":[{"type":"folder","name":"2024","home":"/photos/2024","size":4000}]}`,
+		"/docs/":   `{"type":"folder","home":"/docs","list":[]}`,
+	}
+	var requested []string
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		home := r.URL.Query().Get("home")
+		mu.Lock()
+		requested = append(requested, home)
+		mu.Unlock()
+		_, _ = io.WriteString(w, `{"body":`+folders[home]+`}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	sizes, err := client.StorageBreakdown("/", 1)
+	require.NoError(t, err)
+	require.Len(t, sizes, 2)
+	assert.Equal(t, "/photos", sizes[0].FullPath)
+	assert.Equal(t, int64(5000), sizes[0].Size.DefaultValue)
+	assert.Equal(t, "/docs", sizes[1].FullPath)
+	assert.Equal(t, []string{"/"}, requested)
+
+	sizes, err = client.StorageBreakdown("/", 2)
+	require.NoError(t, err)
+	require.Len(t, sizes, 3)
+	assert.Equal(t, []string{"/photos", "/photos/2024", "/docs"}, []string{sizes[0].FullPath, sizes[1].FullPath, sizes[2].FullPath})
+	assert.Equal(t, 2, sizes[1].Depth)
+}
+
+func TestListAllFiles(t *testing.T) {
+	folders := map[string]string{
+		"/":           `{"type":"folder","home":"/","list":[{"type":"file","name":"b.txt","home":"/b.txt"},{"type":"folder","name":"docs","home":"/docs"},{"type":"folder","name":"broken","home":"/broken"}]}`,
+		"/docs/":      `{"type":"folder","home":"/docs","list":[{"type":"file","name":"a.txt","home":"/docs/a.txt"},{"type":"folder","name":"deep","home":"/docs/deep"}]}`,
+		"/docs/deep/": `{"type":"folder","home":"/docs/deep","list":[{"type":"file","name":"c.txt","home":"/docs/deep/c.txt"}]}`,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		body, ok := folders[r.URL.Query().Get("home")]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = io.WriteString(w, `{"body":`+body+`}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	files, err := client.ListAllFiles("/")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/broken")
+	require.Len(t, files, 3)
+	assert.Equal(t, []string{"/b.txt", "/docs/a.txt", "/docs/deep/c.txt"}, []string{files[0].FullPath, files[1].FullPath, files[2].FullPath})
+}
+
+func TestPublishAllFilesReportsListingErrors(t *testing.T) {
+	folders := map[string]string{
+		"/docs/":     `{"type":"folder","home":"/docs","list":[{"type":"file","name":"a.txt","home":"/docs/a.txt"},{"type":"folder","name":"sub","home":"/docs/sub"},{"type":"folder","name":"broken","home":"/docs/broken"}]}`,
+		"/docs/sub/": `{"type":"folder","home":"/docs/sub","list":[{"type":"file","name":"b.txt","home":"/docs/sub/b.txt"}]}`,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		body, ok := folders[r.URL.Query().Get("home")]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = io.WriteString(w, `{"body":`+body+`}`)
+	})
+	mux.HandleFunc("/api/v2/file/publish", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":"link`+r.FormValue("home")+`"}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	folder, err := client.GetFolder("/docs")
+	require.NoError(t, err)
+
+	links, err := folder.PublishAllFiles(false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"/docs/a.txt": client.Account.buildPublicLink("link/docs/a.txt")}, links)
+
+	links, err = folder.PublishAllFiles(true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/docs/broken")
+	assert.Equal(t, map[string]string{
+		"/docs/a.txt":     client.Account.buildPublicLink("link/docs/a.txt"),
+		"/docs/sub/b.txt": client.Account.buildPublicLink("link/docs/sub/b.txt"),
+	}, links)
+}
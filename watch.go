@@ -0,0 +1,109 @@
+package mailrucloud
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// minWatchInterval минимальный интервал опроса папки в WatchFolder
+const minWatchInterval = time.Second
+
+// WatchFolder наблюдает за папкой path, запрашивая ее список каждые interval (не чаще раза в секунду),
+// и отправляет в канал события о добавленных, удаленных и измененных элементах. Элементы
+// сопоставляются по полному пути, поэтому переименование выглядит как удаление и добавление.
+// Вложенные папки не отслеживаются. Ошибка получения первого списка возвращается сразу,
+// последующие ошибки передаются событиями FolderEventError. Канал закрывается при отмене ctx
+func (c *CloudClient) WatchFolder(ctx context.Context, path string, interval time.Duration) (<-chan FolderEvent, error) {
+	if interval < minWatchInterval {
+		interval = minWatchInterval
+	}
+
+	folder, err := c.GetFolderContext(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan FolderEvent)
+	go func() {
+		defer close(events)
+
+		known := entriesByPath(folder.Items)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			var changes []FolderEvent
+			if folder, err := c.GetFolderContext(ctx, path); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				changes = []FolderEvent{{Kind: FolderEventError, Err: err}}
+			} else {
+				current := entriesByPath(folder.Items)
+				changes = diffFolderEntries(known, current)
+				known = current
+			}
+
+			for _, event := range changes {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// entriesByPath индексирует записи папки по полному пути
+func entriesByPath(items []*CloudStructureEntry) map[string]*CloudStructureEntry {
+	entries := make(map[string]*CloudStructureEntry, len(items))
+	for _, item := range items {
+		entries[item.Home] = item
+	}
+	return entries
+}
+
+// diffFolderEntries сравнивает предыдущий и текущий списки папки и возвращает события,
+// отсортированные по полному пути элемента
+func diffFolderEntries(previous, current map[string]*CloudStructureEntry) []FolderEvent {
+	var events []FolderEvent
+	for home, entry := range current {
+		old, ok := previous[home]
+		switch {
+		case !ok:
+			events = append(events, FolderEvent{Kind: FolderEventAdded, Entry: entry})
+		case entryChanged(old, entry):
+			events = append(events, FolderEvent{Kind: FolderEventChanged, Entry: entry})
+		}
+	}
+	for home, entry := range previous {
+		if _, ok := current[home]; !ok {
+			events = append(events, FolderEvent{Kind: FolderEventRemoved, Entry: entry})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Entry.Home < events[j].Entry.Home
+	})
+	return events
+}
+
+// entryChanged проверяет, что запись элемента изменилась
+func entryChanged(old, entry *CloudStructureEntry) bool {
+	return old.Type != entry.Type ||
+		old.Hash != entry.Hash ||
+		old.Size != entry.Size ||
+		old.Mtime != entry.Mtime ||
+		old.Rev != entry.Rev ||
+		old.Grev != entry.Grev ||
+		old.Weblink != entry.Weblink
+}
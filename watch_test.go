@@ -0,0 +1,63 @@
+package mailrucloud
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchFolder(t *testing.T) {
+	listings := []string{
+		`{"type":"folder","home":"/inbox","list":[{"type":"file","name":"a.txt","home":"/inbox/a.txt","hash":"A1"},{"type":"file","name":"b.txt","home":"/inbox/b.txt","hash":"B1"}]}`,
+		`{"type":"folder","home":"/inbox","list":[{"type":"file","name":"a.txt","home":"/inbox/a.txt","hash":"A2"},{"type":"file","name":"c.txt","home":"/inbox/c.txt","hash":"C1"}]}`,
+	}
+	var mu sync.Mutex
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		listing := listings[min(requests, len(listings)-1)]
+		requests++
+		mu.Unlock()
+		_, _ = io.WriteString(w, `{"body":`+listing+`}`)
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.WatchFolder(ctx, "/inbox", time.Second)
+	require.NoError(t, err)
+
+	var received []FolderEvent
+	for len(received) < 3 {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case <-time.After(5 * time.Second):
+			t.Fatal("события не получены")
+		}
+	}
+
+	assert.Equal(t, FolderEventChanged, received[0].Kind)
+	assert.Equal(t, "A2", received[0].Entry.Hash)
+	assert.Equal(t, FolderEventRemoved, received[1].Kind)
+	assert.Equal(t, "/inbox/b.txt", received[1].Entry.Home)
+	assert.Equal(t, FolderEventAdded, received[2].Kind)
+	assert.Equal(t, "/inbox/c.txt", received[2].Entry.Home)
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		for ok {
+			_, ok = <-events
+		}
+	case <-time.After(time.Second):
+		t.Fatal("канал не закрыт после отмены контекста")
+	}
+}
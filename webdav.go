@@ -0,0 +1,126 @@
+package mailrucloud
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// davMultistatus корневой элемент ответа WebDAV PROPFIND
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"D:multistatus"`
+	XMLNS     string        `xml:"xmlns:D,attr"`
+	Responses []davResponse `xml:"D:response"`
+}
+
+// davResponse описание одного ресурса в ответе PROPFIND
+type davResponse struct {
+	Href     string      `xml:"D:href"`
+	Propstat davPropstat `xml:"D:propstat"`
+}
+
+// davPropstat свойства ресурса со статусом
+type davPropstat struct {
+	Prop   davProp `xml:"D:prop"`
+	Status string  `xml:"D:status"`
+}
+
+// davProp стандартные свойства DAV ресурса
+type davProp struct {
+	DisplayName   string          `xml:"D:displayname"`
+	ResourceType  davResourceType `xml:"D:resourcetype"`
+	ContentLength *int64          `xml:"D:getcontentlength,omitempty"`
+	LastModified  string          `xml:"D:getlastmodified,omitempty"`
+	ETag          string          `xml:"D:getetag,omitempty"`
+	ContentType   string          `xml:"D:getcontenttype,omitempty"`
+}
+
+// davResourceType тип ресурса: коллекция для папок, пустой для файлов
+type davResourceType struct {
+	Collection *struct{} `xml:"D:collection,omitempty"`
+}
+
+// PropfindXML возвращает список элементов по указанному пути в формате WebDAV multistatus XML,
+// как ответ на запрос PROPFIND. depth задает глубину как заголовок Depth: 0 - только сам элемент,
+// 1 - элемент и его непосредственное содержимое, отрицательное значение - без ограничения глубины
+func (c *CloudClient) PropfindXML(path string, depth int) ([]byte, error) {
+	if err := c.checkAuthorization(); err != nil {
+		return nil, err
+	}
+
+	path = c.getPathStartEndSlash(path, true, false)
+	var entry *CloudStructureEntry
+	if path == "/" {
+		entry = &CloudStructureEntry{Type: "folder", Home: "/"}
+	} else {
+		var err error
+		if entry, err = c.getEntry(path); err != nil {
+			return nil, err
+		}
+	}
+
+	status := &davMultistatus{XMLNS: "DAV:"}
+	status.Responses = append(status.Responses, newDavResponse(entry))
+	if err := c.appendPropfindChildren(status, entry, depth); err != nil {
+		return nil, err
+	}
+
+	data, err := xml.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// appendPropfindChildren добавляет в ответ содержимое папки до указанной глубины
+func (c *CloudClient) appendPropfindChildren(status *davMultistatus, entry *CloudStructureEntry, depth int) error {
+	if depth == 0 || !entry.IsFolder() {
+		return nil
+	}
+
+	folder, err := c.GetFolder(entry.Home)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range folder.Items {
+		status.Responses = append(status.Responses, newDavResponse(item))
+		if err := c.appendPropfindChildren(status, item, depth-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newDavResponse отображает запись структуры облака на свойства DAV ресурса
+func newDavResponse(entry *CloudStructureEntry) davResponse {
+	href := (&url.URL{Path: entry.Home}).EscapedPath()
+	prop := davProp{DisplayName: entry.Name}
+
+	if entry.IsFolder() {
+		prop.ResourceType.Collection = &struct{}{}
+		if !strings.HasSuffix(href, "/") {
+			href += "/"
+		}
+	} else {
+		size := entry.Size
+		prop.ContentLength = &size
+		prop.ContentType = "application/octet-stream"
+		if entry.Hash != "" {
+			prop.ETag = `"` + entry.Hash + `"`
+		}
+	}
+
+	if mtime := unixTimeUTC(entry.Mtime); !mtime.IsZero() {
+		prop.LastModified = mtime.Format(http.TimeFormat)
+	}
+
+	return davResponse{
+		Href: href,
+		Propstat: davPropstat{
+			Prop:   prop,
+			Status: "HTTP/1.1 200 OK",
+		},
+	}
+}
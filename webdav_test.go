@@ -0,0 +1,29 @@
+package mailrucloud
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDavResponse(t *testing.T) {
+	status := &davMultistatus{XMLNS: "DAV:"}
+	status.Responses = append(status.Responses,
+		newDavResponse(&CloudStructureEntry{Type: "folder", Name: "Docs", Home: "/Docs"}),
+		newDavResponse(&CloudStructureEntry{Type: "file", Name: "a b.txt", Home: "/Docs/a b.txt", Size: 42, Hash: "ABC", Mtime: 1700000000}),
+	)
+
+	data, err := xml.Marshal(status)
+	require.NoError(t, err)
+	out := string(data)
+
+	assert.Contains(t, out, `<D:multistatus xmlns:D="DAV:">`)
+	assert.Contains(t, out, `<D:href>/Docs/</D:href>`)
+	assert.Contains(t, out, `<D:resourcetype><D:collection></D:collection></D:resourcetype>`)
+	assert.Contains(t, out, `<D:href>/Docs/a%20b.txt</D:href>`)
+	assert.Contains(t, out, `<D:getcontentlength>42</D:getcontentlength>`)
+	assert.Contains(t, out, `<D:getlastmodified>Tue, 14 Nov 2023 22:13:20 GMT</D:getlastmodified>`)
+	assert.Contains(t, out, `<D:getetag>&#34;ABC&#34;</D:getetag>`)
+}
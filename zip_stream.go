@@ -0,0 +1,176 @@
+package mailrucloud
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// zipStreamEntry файл, добавляемый в клиентский ZIP архив
+type zipStreamEntry struct {
+	// entry метаданные файла в облаке
+	entry *CloudStructureEntry
+	// name путь файла внутри архива
+	name string
+}
+
+// StreamZIP скачивает выбранные файлы и папки и записывает их в w как ZIP архив, формируемый на стороне
+// клиента. В отличие от DownloadItemsAsZIPArchive элементы могут находиться в разных папках, а размер
+// архива не ограничен лимитом сервера. Каждый элемент попадает в архив под своим именем, содержимое
+// папок - с путями относительно выбранной папки. Если имена файлов из разных папок совпадают,
+// к повторным именам добавляется номер, например "x (1).txt"
+func (c *CloudClient) StreamZIP(paths []string, w io.Writer) error {
+	return c.StreamZIPContext(context.Background(), paths, w)
+}
+
+// StreamZIPContext формирует клиентский ZIP архив с учетом контекста. Ошибка получения или скачивания
+// отдельного элемента не прерывает формирование архива: такие элементы пропускаются (или остаются
+// усеченными, если ошибка произошла во время передачи), а все ошибки возвращаются вместе через
+// errors.Join после записи архива. Отмена ctx прерывает формирование архива, в том числе
+// выполняющиеся запросы
+func (c *CloudClient) StreamZIPContext(ctx context.Context, paths []string, w io.Writer) error {
+	if len(paths) == 0 {
+		return &CloudClientError{
+			Message:   "Список путей не может быть пустым",
+			ErrorCode: ErrorCodePathNotExists,
+		}
+	}
+
+	// Скачивание прерывается и при отмене ctx, и при остановке клиента через Shutdown
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(c.cancelCtx, cancel)
+	defer stop()
+
+	entries, errs := c.collectZIPEntries(ctx, paths)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	archive := zip.NewWriter(w)
+	for _, item := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := c.writeZIPEntry(ctx, archive, item); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", item.entry.Home, err))
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// collectZIPEntries получает список файлов выбранных элементов, раскрывая папки рекурсивно
+func (c *CloudClient) collectZIPEntries(ctx context.Context, paths []string) ([]zipStreamEntry, []error) {
+	var (
+		mu      sync.Mutex
+		entries []zipStreamEntry
+		errs    []error
+	)
+
+	for _, path := range paths {
+		entry, err := c.statContext(ctx, path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		base := c.getParentCloudPath(entry.Home)
+		if !entry.IsFolder() {
+			entries = append(entries, zipStreamEntry{entry: entry, name: strings.TrimPrefix(entry.Home, base)})
+			continue
+		}
+
+		root, err := c.GetFolderContext(ctx, entry.Home)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		err = c.walkFolders(ctx, root, -1, defaultWalkConcurrency, func(folder *Folder, depth int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, item := range folder.Items {
+				if !item.IsFolder() {
+					entries = append(entries, zipStreamEntry{entry: item, name: strings.TrimPrefix(item.Home, base)})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+	uniqueZIPNames(entries)
+	return entries, errs
+}
+
+// uniqueZIPNames добавляет номер к совпадающим именам файлов архива, например при выборе
+// "/a/x.txt" и "/b/x.txt". Первый файл сохраняет имя, следующие получают "x (1).txt", "x (2).txt"
+func uniqueZIPNames(entries []zipStreamEntry) {
+	used := make(map[string]bool, len(entries))
+	for i := range entries {
+		name := entries[i].name
+		if used[name] {
+			extension := path.Ext(name)
+			for n := 1; used[name]; n++ {
+				name = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(entries[i].name, extension), n, extension)
+			}
+			entries[i].name = name
+		}
+		used[name] = true
+	}
+}
+
+// writeZIPEntry скачивает файл и записывает его в архив
+func (c *CloudClient) writeZIPEntry(ctx context.Context, archive *zip.Writer, item zipStreamEntry) error {
+	stream, _, err := c.downloadFromGetShard(ctx, strings.TrimPrefix(item.entry.Home, "/"), nil, time.Time{})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	header := &zip.FileHeader{
+		Name:     item.name,
+		Method:   zip.Deflate,
+		Modified: unixTimeUTC(item.entry.Mtime),
+	}
+	dst, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.copyBuffer(dst, &contextReader{ctx: ctx, reader: stream})
+	return err
+}
+
+// contextReader поток чтения, прерываемый отменой контекста
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+// Read читает данные, если контекст не отменен
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
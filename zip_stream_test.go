@@ -0,0 +1,113 @@
+package mailrucloud
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamZIP(t *testing.T) {
+	entries := map[string]string{
+		"/docs/a.txt": `{"type":"file","name":"a.txt","home":"/docs/a.txt","size":1}`,
+		"/photos":     `{"type":"folder","name":"photos","home":"/photos"}`,
+	}
+	folders := map[string]string{
+		"/photos/":      `{"type":"folder","name":"photos","home":"/photos","list":[{"type":"file","name":"b.jpg","home":"/photos/b.jpg"},{"type":"folder","name":"2024","home":"/photos/2024"}]}`,
+		"/photos/2024/": `{"type":"folder","name":"2024","home":"/photos/2024","list":[{"type":"file","name":"c.jpg","home":"/photos/2024/c.jpg"}]}`,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		entry, ok := entries[r.URL.Query().Get("home")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `{"body":`+entry+`}`)
+	})
+	mux.HandleFunc("/api/v2/folder", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":`+folders[r.URL.Query().Get("home")]+`}`)
+	})
+	mux.HandleFunc("/get/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "content of "+strings.TrimPrefix(r.URL.Path, "/get"))
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	var buf bytes.Buffer
+	err := client.StreamZIP([]string{"/docs/a.txt", "/photos", "/missing.txt"}, &buf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/missing.txt")
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	contents := map[string]string{}
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		contents[file.Name] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		"a.txt":             "content of /docs/a.txt",
+		"photos/2024/c.jpg": "content of /photos/2024/c.jpg",
+		"photos/b.jpg":      "content of /photos/b.jpg",
+	}, contents)
+}
+
+func TestStreamZIPDuplicateNames(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		home := r.URL.Query().Get("home")
+		_, _ = io.WriteString(w, `{"body":{"type":"file","name":"x.txt","home":"`+home+`","size":1}}`)
+	})
+	mux.HandleFunc("/get/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "content of "+strings.TrimPrefix(r.URL.Path, "/get"))
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	var buf bytes.Buffer
+	require.NoError(t, client.StreamZIP([]string{"/a/x.txt", "/b/x.txt"}, &buf))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 2)
+	assert.Equal(t, "x.txt", archive.File[0].Name)
+	assert.Equal(t, "x (1).txt", archive.File[1].Name)
+}
+
+func TestStreamZIPContextCancelsStalledDownload(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/file", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"body":{"type":"file","name":"a.txt","home":"/a.txt","size":100}}`)
+	})
+	mux.HandleFunc("/get/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		_, _ = io.WriteString(w, "partial")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	client, _ := newFakeCloud(t, mux)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := client.StreamZIPContext(ctx, []string{"/a.txt"}, io.Discard)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}